@@ -0,0 +1,112 @@
+package oss
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/www-xu/spark/httpclient"
+)
+
+const defaultPresignExpires = 15 * time.Minute
+
+// BucketClient works with the objects of a bucket declared in alicloud_oss.buckets.
+type BucketClient struct {
+	name           string
+	bucket         string
+	endpoint       string
+	region         string
+	client         *oss.Client
+	upload         UploadConfig
+	presignExpires time.Duration
+	httpClient     *http.Client
+}
+
+func (c *Component) newBuckets() error {
+	c.buckets = make(map[string]*BucketClient, len(c.config.Buckets))
+
+	presignExpires := c.config.PresignExpires
+	if presignExpires <= 0 {
+		presignExpires = defaultPresignExpires
+	}
+
+	upload := c.config.Upload
+	if upload.CheckpointDir == "" {
+		upload.CheckpointDir = filepath.Join(os.TempDir(), "oss-checkpoint")
+	}
+
+	httpClient := httpclient.New(httpclient.Config{Name: "oss"})
+
+	for name, bucketConfig := range c.config.Buckets {
+		if bucketConfig.Name == "" {
+			return fmt.Errorf("alicloud_oss bucket %s: name is required", name)
+		}
+
+		client := c.instance
+		endpoint, region := c.config.Endpoint, c.config.Region
+		if bucketConfig.Endpoint != "" || bucketConfig.Region != "" {
+			if bucketConfig.Endpoint != "" {
+				endpoint = bucketConfig.Endpoint
+			}
+			if bucketConfig.Region != "" {
+				region = bucketConfig.Region
+			}
+			client = newClient(endpoint, region)
+		}
+
+		c.buckets[name] = &BucketClient{
+			name:           name,
+			bucket:         bucketConfig.Name,
+			endpoint:       endpoint,
+			region:         region,
+			client:         client,
+			upload:         upload,
+			presignExpires: presignExpires,
+			httpClient:     httpClient,
+		}
+	}
+
+	return nil
+}
+
+func newClient(endpoint, region string) *oss.Client {
+	cfg := oss.LoadDefaultConfig().
+		WithEndpoint(endpoint).
+		WithRegion(region).
+		WithCredentialsProvider(credentialsProvider)
+
+	return oss.NewClient(cfg)
+}
+
+// credentialsProvider reads ALIBABA_CLOUD_ACCESS_KEY_ID, ALIBABA_CLOUD_ACCESS_KEY_SECRET and
+// ALIBABA_CLOUD_SECURITY_TOKEN, it is shared by the SDK clients and PostPolicy.
+var credentialsProvider = credentials.NewEnvironmentVariableCredentialsProvider()
+
+// Bucket returns the client of the bucket declared as name in alicloud_oss.buckets.
+func Bucket(ctx context.Context, name string) (*BucketClient, error) {
+	return instance.Bucket(ctx, name)
+}
+
+func (c *Component) Bucket(ctx context.Context, name string) (*BucketClient, error) {
+	bucket, ok := c.buckets[name]
+	if !ok {
+		return nil, fmt.Errorf("alicloud_oss bucket %s isn't declared", name)
+	}
+
+	return bucket, nil
+}
+
+// Name returns the bucket name in OSS.
+func (b *BucketClient) Name() string {
+	return b.bucket
+}
+
+// Client returns the SDK client used for the bucket.
+func (b *BucketClient) Client() *oss.Client {
+	return b.client
+}
@@ -0,0 +1,235 @@
+package oss
+
+import (
+	"context"
+	"crypto"
+	"crypto/md5"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/www-xu/spark/httpclient"
+	"github.com/www-xu/spark/log"
+)
+
+const (
+	PubKeyURLHeader = "x-oss-pub-key-url"
+
+	maxCallbackBodyBytes = 1 << 20
+
+	// forged callbacks may reference any URL under pubKeyURLPrefix, so after a failed fetch no key is
+	// fetched for pubKeyFailureDelay and the failed URL isn't fetched again for pubKeyFailureTTL
+	pubKeyFailureDelay = time.Second
+	pubKeyFailureTTL   = time.Minute
+)
+
+// public keys are only fetched over https from OSS's own host, otherwise anyone could sign callbacks
+// with their key. OSS sends http urls, they are upgraded to https before the check.
+const pubKeyURLPrefix = "https://gosspublic.alicdn.com/"
+
+var (
+	ErrInvalidCallbackSignature = errors.New("invalid oss callback signature")
+
+	pubKeys          sync.Map // public key URL => *rsa.PublicKey
+	pubKeyFetches    = &pubKeyLimiter{failures: map[string]time.Time{}}
+	pubKeyHTTPClient = httpclient.New(httpclient.Config{Name: "oss-callback", Timeout: 5 * time.Second})
+)
+
+// pubKeyLimiter bounds the outbound fetches of public keys that aren't cached.
+type pubKeyLimiter struct {
+	mu          sync.Mutex
+	lastFailure time.Time
+	failures    map[string]time.Time // URL => when it may be fetched again
+}
+
+// allow returns an error when url may not be fetched now.
+func (l *pubKeyLimiter) allow(url string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if until, ok := l.failures[url]; ok && now.Before(until) {
+		return fmt.Errorf("%w: fetching public key %s failed recently", ErrInvalidCallbackSignature, url)
+	}
+	if now.Sub(l.lastFailure) < pubKeyFailureDelay {
+		return fmt.Errorf("%w: too many failed public key fetches", ErrInvalidCallbackSignature)
+	}
+
+	return nil
+}
+
+func (l *pubKeyLimiter) fail(url string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for failed, until := range l.failures {
+		if now.After(until) {
+			delete(l.failures, failed)
+		}
+	}
+	l.failures[url] = now.Add(pubKeyFailureTTL)
+	l.lastFailure = now
+}
+
+// UploadCallback is the body OSS sends after a PostObject upload with the default Callback.Body.
+type UploadCallback struct {
+	Bucket   string `json:"bucket"`
+	Object   string `json:"object"`
+	ETag     string `json:"etag"`
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	// Raw is the body as sent, decode it with Decode when Callback.Body is customized.
+	Raw []byte `json:"-"`
+}
+
+// Decode decodes the raw JSON body into v.
+func (c *UploadCallback) Decode(v any) error {
+	return json.Unmarshal(c.Raw, v)
+}
+
+// UploadCallbackFunc handles a verified callback. The returned value is encoded as JSON and relayed by
+// OSS to the uploading browser, returning an error fails the upload.
+type UploadCallbackFunc func(ctx context.Context, callback *UploadCallback) (any, error)
+
+// UploadCallbackHandler returns a gin handler for Callback.URL that verifies the RSA signature OSS puts
+// in the Authorization header, using the public key referenced by x-oss-pub-key-url, before calling handler.
+func UploadCallbackHandler(handler UploadCallbackFunc) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, maxCallbackBodyBytes))
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+			return
+		}
+
+		err = verifyCallback(ctx.Request.Context(), ctx.Request, body)
+		if err != nil {
+			log.WithContext(ctx.Request.Context()).WithError(err).Warn("rejected oss upload callback")
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": ErrInvalidCallbackSignature.Error()})
+			return
+		}
+
+		callback := &UploadCallback{Raw: body}
+		// custom bodies may not be JSON, handlers read them from Raw
+		_ = json.Unmarshal(body, callback)
+
+		response, err := handler(ctx.Request.Context(), callback)
+		if err != nil {
+			log.WithContext(ctx.Request.Context()).WithError(err).WithField("object", callback.Object).Error("failed to handle oss upload callback")
+			// OSS relays the body to the uploading browser, so the details only go to the log
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to handle upload callback"})
+			return
+		}
+
+		if response == nil {
+			response = gin.H{}
+		}
+		ctx.JSON(http.StatusOK, response)
+	}
+}
+
+// verifyCallback checks the signature of the decoded path, the raw query and the body, as described in
+// https://help.aliyun.com/zh/oss/developer-reference/callback.
+func verifyCallback(ctx context.Context, request *http.Request, body []byte) error {
+	signature, err := base64.StdEncoding.DecodeString(request.Header.Get("Authorization"))
+	if err != nil || len(signature) == 0 {
+		return fmt.Errorf("%w: malformed authorization", ErrInvalidCallbackSignature)
+	}
+
+	pubKeyURL, err := base64.StdEncoding.DecodeString(request.Header.Get(PubKeyURLHeader))
+	if err != nil {
+		return fmt.Errorf("%w: malformed %s", ErrInvalidCallbackSignature, PubKeyURLHeader)
+	}
+
+	pubKey, err := publicKey(ctx, string(pubKeyURL))
+	if err != nil {
+		return err
+	}
+
+	authString := request.URL.Path
+	if request.URL.RawQuery != "" {
+		authString += "?" + request.URL.RawQuery
+	}
+	authString += "\n" + string(body)
+
+	digest := md5.Sum([]byte(authString))
+	err = rsa.VerifyPKCS1v15(pubKey, crypto.MD5, digest[:], signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCallbackSignature, err)
+	}
+
+	return nil
+}
+
+func publicKey(ctx context.Context, url string) (*rsa.PublicKey, error) {
+	if rest, ok := strings.CutPrefix(url, "http://"); ok {
+		url = "https://" + rest
+	}
+	if !strings.HasPrefix(url, pubKeyURLPrefix) {
+		return nil, fmt.Errorf("%w: untrusted public key url %s", ErrInvalidCallbackSignature, url)
+	}
+
+	if pubKey, ok := pubKeys.Load(url); ok {
+		return pubKey.(*rsa.PublicKey), nil
+	}
+
+	err := pubKeyFetches.allow(url)
+	if err != nil {
+		return nil, err
+	}
+
+	pubKey, err := fetchPublicKey(ctx, url)
+	if err != nil {
+		pubKeyFetches.fail(url)
+		return nil, err
+	}
+
+	pubKeys.Store(url, pubKey)
+
+	return pubKey, nil
+}
+
+func fetchPublicKey(ctx context.Context, url string) (*rsa.PublicKey, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := pubKeyHTTPClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("fetch oss public key: %w", err)
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, 16<<10))
+	if err != nil {
+		return nil, fmt.Errorf("fetch oss public key: %w", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch oss public key: [%s] | %s", response.Status, string(data))
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("oss public key isn't PEM encoded")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse oss public key: %w", err)
+	}
+	pubKey, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("oss public key isn't an RSA key")
+	}
+
+	return pubKey, nil
+}
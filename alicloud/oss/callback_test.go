@@ -0,0 +1,250 @@
+package oss
+
+import (
+	"context"
+	"crypto"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testPubKeyURL = "http://gosspublic.alicdn.com/callback_pub_key_v1.pem"
+
+var (
+	testKeyOnce sync.Once
+	testKey     *rsa.PrivateKey
+)
+
+func testPrivateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	testKeyOnce.Do(func() {
+		var err error
+		testKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	return testKey
+}
+
+// resetPubKeys empties the public key caches, and the limiter of failed fetches, for the test.
+func resetPubKeys(t *testing.T) {
+	t.Helper()
+
+	reset := func() {
+		pubKeys.Range(func(key, value any) bool {
+			pubKeys.Delete(key)
+			return true
+		})
+		pubKeyFetches = &pubKeyLimiter{failures: map[string]time.Time{}}
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+// signCallback signs a callback the way OSS does, over the path, the query and the body.
+func signCallback(t *testing.T, request *http.Request, body string) {
+	t.Helper()
+
+	authString := request.URL.Path
+	if request.URL.RawQuery != "" {
+		authString += "?" + request.URL.RawQuery
+	}
+	digest := md5.Sum([]byte(authString + "\n" + body))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, testPrivateKey(t), crypto.MD5, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request.Header.Set("Authorization", base64.StdEncoding.EncodeToString(signature))
+	request.Header.Set(PubKeyURLHeader, base64.StdEncoding.EncodeToString([]byte(testPubKeyURL)))
+}
+
+func TestUploadCallbackHandler(t *testing.T) {
+	resetPubKeys(t)
+	pubKeys.Store("https://gosspublic.alicdn.com/callback_pub_key_v1.pem", &testPrivateKey(t).PublicKey)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	var received *UploadCallback
+	engine.POST("/oss/callback", UploadCallbackHandler(func(ctx context.Context, callback *UploadCallback) (any, error) {
+		if callback.Object == "uploads/fail.png" {
+			return nil, errors.New("insert upload: connection refused")
+		}
+		received = callback
+		return gin.H{"url": "/files/" + callback.Object}, nil
+	}))
+
+	body := `{"bucket":"media","object":"uploads/a.png","etag":"E1","size":42,"mimeType":"image/png"}`
+	// post sends sent to target, signed as body posted to /oss/callback?user=u1
+	post := func(target, sent string) *httptest.ResponseRecorder {
+		signed := httptest.NewRequest(http.MethodPost, "/oss/callback?user=u1", nil)
+		signCallback(t, signed, body)
+
+		request := httptest.NewRequest(http.MethodPost, target, strings.NewReader(sent))
+		request.Header = signed.Header
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := post("/oss/callback?user=u1", body)
+	if recorder.Code != http.StatusOK || recorder.Body.String() != `{"url":"/files/uploads/a.png"}` {
+		t.Errorf("valid callback: %d %s", recorder.Code, recorder.Body)
+	}
+	if received == nil || received.Bucket != "media" || received.Size != 42 || received.MimeType != "image/png" || string(received.Raw) != body {
+		t.Errorf("unexpected callback %+v", received)
+	}
+
+	recorder = post("/oss/callback?user=u1", strings.Replace(body, "a.png", "b.png", 1))
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("tampered body: status = %d, want 403", recorder.Code)
+	}
+
+	recorder = post("/oss/callback?user=u2", body)
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("tampered query: status = %d, want 403", recorder.Code)
+	}
+
+	failing := `{"bucket":"media","object":"uploads/fail.png"}`
+	request := httptest.NewRequest(http.MethodPost, "/oss/callback", strings.NewReader(failing))
+	signCallback(t, request, failing)
+	recorder = httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusInternalServerError || strings.Contains(recorder.Body.String(), "connection refused") {
+		t.Errorf("failing handler: %d %s, want 500 without the error", recorder.Code, recorder.Body)
+	}
+}
+
+func TestVerifyCallbackUntrustedPubKeyURL(t *testing.T) {
+	resetPubKeys(t)
+
+	var fetches atomic.Int32
+	usePubKeyServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+	})
+
+	for _, pubKeyURL := range []string{
+		"https://attacker.example/callback_pub_key_v1.pem",
+		"https://gosspublic.alicdn.com.attacker.example/key.pem",
+		"ftp://gosspublic.alicdn.com/key.pem",
+	} {
+		body := `{"object":"uploads/a.png"}`
+		request := httptest.NewRequest(http.MethodPost, "/oss/callback", strings.NewReader(body))
+		signCallback(t, request, body)
+		request.Header.Set(PubKeyURLHeader, base64.StdEncoding.EncodeToString([]byte(pubKeyURL)))
+
+		err := verifyCallback(context.Background(), request, []byte(body))
+		if !errors.Is(err, ErrInvalidCallbackSignature) {
+			t.Errorf("%s: err = %v, want ErrInvalidCallbackSignature", pubKeyURL, err)
+		}
+	}
+
+	if fetches.Load() != 0 {
+		t.Errorf("untrusted keys fetched %d times", fetches.Load())
+	}
+}
+
+// usePubKeyServer sends the public key fetches to handler instead of gosspublic.alicdn.com.
+func usePubKeyServer(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	target, _ := url.Parse(server.URL)
+
+	client := pubKeyHTTPClient
+	pubKeyHTTPClient = &http.Client{Transport: roundTripFunc(func(request *http.Request) (*http.Response, error) {
+		request = request.Clone(request.Context())
+		request.URL.Scheme, request.URL.Host = target.Scheme, target.Host
+		return http.DefaultTransport.RoundTrip(request)
+	})}
+	t.Cleanup(func() { pubKeyHTTPClient = client })
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+func TestPublicKeyFetch(t *testing.T) {
+	resetPubKeys(t)
+
+	der, err := x509.MarshalPKIXPublicKey(&testPrivateKey(t).PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	fetches := map[string]int{}
+	var mu sync.Mutex
+	usePubKeyServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fetches[r.URL.Path]++
+		mu.Unlock()
+
+		if r.URL.Path != "/callback_pub_key_v1.pem" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(pemKey)
+	})
+	fetched := func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return fetches[path]
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		pubKey, err := publicKey(ctx, testPubKeyURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pubKey.Equal(&testPrivateKey(t).PublicKey) {
+			t.Error("fetched a different key")
+		}
+	}
+	if fetched("/callback_pub_key_v1.pem") != 1 {
+		t.Errorf("key fetched %d times, want it cached", fetched("/callback_pub_key_v1.pem"))
+	}
+
+	// a forged URL fails once, then neither it nor any other new URL is fetched for a while
+	_, err = publicKey(ctx, "http://gosspublic.alicdn.com/forged-1.pem")
+	if err == nil {
+		t.Fatal("want an error for a missing key")
+	}
+	for _, forged := range []string{"forged-1.pem", "forged-2.pem"} {
+		_, err = publicKey(ctx, "http://gosspublic.alicdn.com/"+forged)
+		if !errors.Is(err, ErrInvalidCallbackSignature) {
+			t.Errorf("%s: err = %v, want ErrInvalidCallbackSignature", forged, err)
+		}
+	}
+	if fetched("/forged-1.pem") != 1 || fetched("/forged-2.pem") != 0 {
+		t.Errorf("forged keys fetched %d and %d times, want a single fetch", fetched("/forged-1.pem"), fetched("/forged-2.pem"))
+	}
+
+	// cached keys aren't affected
+	_, err = publicKey(ctx, testPubKeyURL)
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"errors"
 
 	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
-	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
 	"github.com/www-xu/spark"
 )
 
@@ -13,6 +12,7 @@ type Component struct {
 	ctx      *spark.ApplicationContext
 	config   *Config
 	instance *oss.Client
+	buckets  map[string]*BucketClient
 }
 
 func NewComponent() *Component {
@@ -38,12 +38,12 @@ func (c *Component) Instantiate() (err error) {
 		return errors.New("alicloud_oss config isn't found")
 	}
 
-	cfg := oss.LoadDefaultConfig().
-		WithEndpoint(c.config.Endpoint).
-		WithRegion(c.config.Region).
-		WithCredentialsProvider(credentials.NewEnvironmentVariableCredentialsProvider())
+	c.instance = newClient(c.config.Endpoint, c.config.Region)
 
-	c.instance = oss.NewClient(cfg)
+	err = c.newBuckets()
+	if err != nil {
+		return err
+	}
 
 	return nil
 }
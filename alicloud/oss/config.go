@@ -1,6 +1,25 @@
 package oss
 
+import "time"
+
+type BucketConfig struct {
+	Name     string `json:"name" mapstructure:"name"`         // bucket name in OSS
+	Endpoint string `json:"endpoint" mapstructure:"endpoint"` // defaults to alicloud_oss.endpoint
+	Region   string `json:"region" mapstructure:"region"`     // defaults to alicloud_oss.region
+}
+
+type UploadConfig struct {
+	PartSize    int64 `json:"part_size" mapstructure:"part_size"`       // bytes per part, defaults to the SDK's 6 MiB
+	ParallelNum int   `json:"parallel_num" mapstructure:"parallel_num"` // parts uploaded at once, defaults to the SDK's 3
+	// CheckpointDir keeps the progress of UploadFile so that an interrupted upload resumes, defaults to the temp dir.
+	CheckpointDir string `json:"checkpoint_dir" mapstructure:"checkpoint_dir"`
+}
+
 type Config struct {
-	Endpoint string `json:"endpoint" mapstructure:"endpoint"`
-	Region   string `json:"region" mapstructure:"region"`
+	Endpoint string                  `json:"endpoint" mapstructure:"endpoint"`
+	Region   string                  `json:"region" mapstructure:"region"`
+	Buckets  map[string]BucketConfig `json:"buckets" mapstructure:"buckets"` // key is the name used by Bucket
+	Upload   UploadConfig            `json:"upload" mapstructure:"upload"`
+	// PresignExpires is the expiry of presigned URLs when callers pass 0, defaults to 15 minutes.
+	PresignExpires time.Duration `json:"presign_expires" mapstructure:"presign_expires"`
 }
@@ -0,0 +1,155 @@
+package oss
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
+)
+
+type objectOptions struct {
+	contentType        string
+	contentDisposition string
+	metadata           map[string]string
+}
+
+// ObjectOption sets headers of uploaded objects, presigned URLs and proxied downloads.
+type ObjectOption func(*objectOptions)
+
+// WithContentType sets the content type of an upload. For PresignPut the client must send the same
+// Content-Type header, for PresignGet and ProxyDownload it overrides the content type of the response.
+func WithContentType(contentType string) ObjectOption {
+	return func(o *objectOptions) {
+		o.contentType = contentType
+	}
+}
+
+// WithContentDisposition sets the Content-Disposition of an upload or of the downloaded response,
+// e.g. `attachment; filename="report.pdf"`.
+func WithContentDisposition(contentDisposition string) ObjectOption {
+	return func(o *objectOptions) {
+		o.contentDisposition = contentDisposition
+	}
+}
+
+// WithMetadata sets the user metadata (x-oss-meta-*) of an upload.
+func WithMetadata(metadata map[string]string) ObjectOption {
+	return func(o *objectOptions) {
+		o.metadata = metadata
+	}
+}
+
+func newObjectOptions(opts []ObjectOption) objectOptions {
+	var options objectOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+func (b *BucketClient) putObjectRequest(key string, options objectOptions) *oss.PutObjectRequest {
+	request := &oss.PutObjectRequest{
+		Bucket:   oss.Ptr(b.bucket),
+		Key:      oss.Ptr(key),
+		Metadata: options.metadata,
+	}
+	if options.contentType != "" {
+		request.ContentType = oss.Ptr(options.contentType)
+	}
+	if options.contentDisposition != "" {
+		request.ContentDisposition = oss.Ptr(options.contentDisposition)
+	}
+	return request
+}
+
+func (b *BucketClient) uploaderOptions(uo *oss.UploaderOptions) {
+	if b.upload.PartSize > 0 {
+		uo.PartSize = b.upload.PartSize
+	}
+	if b.upload.ParallelNum > 0 {
+		uo.ParallelNum = b.upload.ParallelNum
+	}
+}
+
+// Upload streams r to key. Bodies larger than a part are sent as a multipart upload whose parts are
+// uploaded in parallel, so the size of r doesn't need to be known in advance. A failed upload can't be
+// resumed since the parts already read from r can't be read again, use UploadFile for large files.
+func (b *BucketClient) Upload(ctx context.Context, key string, r io.Reader, opts ...ObjectOption) (*oss.UploadResult, error) {
+	uploader := b.client.NewUploader(b.uploaderOptions)
+
+	result, err := uploader.UploadFrom(ctx, b.putObjectRequest(key, newObjectOptions(opts)), r)
+	if err != nil {
+		return nil, fmt.Errorf("upload oss object %s/%s: %w", b.bucket, key, err)
+	}
+
+	return result, nil
+}
+
+// UploadFile uploads the file at path to key with a checkpoint in upload.checkpoint_dir, calling it
+// again after a failure resumes from the parts already uploaded.
+func (b *BucketClient) UploadFile(ctx context.Context, key, path string, opts ...ObjectOption) (*oss.UploadResult, error) {
+	uploader := b.client.NewUploader(b.uploaderOptions, func(uo *oss.UploaderOptions) {
+		uo.EnableCheckpoint = true
+		uo.CheckpointDir = b.upload.CheckpointDir
+	})
+
+	result, err := uploader.UploadFile(ctx, b.putObjectRequest(key, newObjectOptions(opts)), path)
+	if err != nil {
+		return nil, fmt.Errorf("upload file %s to oss object %s/%s: %w", path, b.bucket, key, err)
+	}
+
+	return result, nil
+}
+
+// Download streams the object to w and returns the number of bytes written.
+func (b *BucketClient) Download(ctx context.Context, key string, w io.Writer) (int64, error) {
+	result, err := b.client.GetObject(ctx, &oss.GetObjectRequest{
+		Bucket: oss.Ptr(b.bucket),
+		Key:    oss.Ptr(key),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("download oss object %s/%s: %w", b.bucket, key, err)
+	}
+	defer result.Body.Close()
+
+	return io.Copy(w, result.Body)
+}
+
+// PresignGet returns a URL that downloads the object until it expires, 0 means presign_expires.
+func (b *BucketClient) PresignGet(ctx context.Context, key string, expires time.Duration, opts ...ObjectOption) (*oss.PresignResult, error) {
+	options := newObjectOptions(opts)
+
+	request := &oss.GetObjectRequest{
+		Bucket: oss.Ptr(b.bucket),
+		Key:    oss.Ptr(key),
+	}
+	if options.contentType != "" {
+		request.ResponseContentType = oss.Ptr(options.contentType)
+	}
+	if options.contentDisposition != "" {
+		request.ResponseContentDisposition = oss.Ptr(options.contentDisposition)
+	}
+
+	return b.presign(ctx, request, expires)
+}
+
+// PresignPut returns a URL that uploads key until it expires, 0 means presign_expires. With WithContentType
+// the content type is part of the signature, so the client must send the headers in SignedHeaders.
+func (b *BucketClient) PresignPut(ctx context.Context, key string, expires time.Duration, opts ...ObjectOption) (*oss.PresignResult, error) {
+	return b.presign(ctx, b.putObjectRequest(key, newObjectOptions(opts)), expires)
+}
+
+func (b *BucketClient) presign(ctx context.Context, request any, expires time.Duration) (*oss.PresignResult, error) {
+	if expires <= 0 {
+		expires = b.presignExpires
+	}
+
+	result, err := b.client.Presign(ctx, request, oss.PresignExpires(expires))
+	if err != nil {
+		return nil, fmt.Errorf("presign oss bucket %s: %w", b.bucket, err)
+	}
+
+	return result, nil
+}
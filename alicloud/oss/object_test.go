@@ -0,0 +1,163 @@
+package oss
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
+)
+
+// newTestBucket returns the client of bucket media on an OSS server handled by handler, addressed
+// with path style URLs such as /media/key.
+func newTestBucket(t *testing.T, handler http.HandlerFunc) *BucketClient {
+	t.Helper()
+	useTestCredentials(t, "")
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cfg := oss.LoadDefaultConfig().
+		WithEndpoint(server.URL).
+		WithRegion("cn-hangzhou").
+		WithCredentialsProvider(credentialsProvider).
+		WithUsePathStyle(true).
+		WithRetryMaxAttempts(1)
+
+	return &BucketClient{
+		bucket:         "media",
+		region:         "cn-hangzhou",
+		client:         oss.NewClient(cfg),
+		presignExpires: 15 * time.Minute,
+		httpClient:     server.Client(),
+	}
+}
+
+func TestPresignPut(t *testing.T) {
+	bucket := newTestBucket(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("presigning sent %s %s", r.Method, r.URL)
+	})
+	ctx := context.Background()
+
+	presigned, err := bucket.PresignPut(ctx, "uploads/a.png", 0, WithContentType("image/png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if presigned.Method != http.MethodPut || !strings.Contains(presigned.URL, "/media/uploads/a.png?") {
+		t.Errorf("unexpected presigned %s %s", presigned.Method, presigned.URL)
+	}
+	// the content type is signed, so the client must send it
+	if presigned.SignedHeaders["Content-Type"] != "image/png" {
+		t.Errorf("signed headers = %v, want the content type", presigned.SignedHeaders)
+	}
+	if until := time.Until(presigned.Expiration); until < 14*time.Minute || until > 15*time.Minute {
+		t.Errorf("expiration = %s, want presign_expires from now", presigned.Expiration)
+	}
+
+	query := presignedQuery(t, presigned)
+	if query.Get("x-oss-signature") == "" || query.Get("x-oss-expires") != "900" {
+		t.Errorf("unexpected query %v", query)
+	}
+
+	presigned, err = bucket.PresignPut(ctx, "uploads/a.png", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := presigned.SignedHeaders["Content-Type"]; ok {
+		t.Errorf("signed headers = %v, want no content type", presigned.SignedHeaders)
+	}
+	if query := presignedQuery(t, presigned); query.Get("x-oss-expires") != "60" {
+		t.Errorf("x-oss-expires = %s, want 60", query.Get("x-oss-expires"))
+	}
+}
+
+func TestPresignGet(t *testing.T) {
+	bucket := newTestBucket(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("presigning sent %s %s", r.Method, r.URL)
+	})
+
+	presigned, err := bucket.PresignGet(context.Background(), "reports/r1.pdf", 0,
+		WithContentType("application/pdf"), WithContentDisposition(`attachment; filename="report.pdf"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := presignedQuery(t, presigned)
+	if query.Get("response-content-type") != "application/pdf" || query.Get("response-content-disposition") != `attachment; filename="report.pdf"` {
+		t.Errorf("unexpected query %v", query)
+	}
+	if len(presigned.SignedHeaders) != 0 {
+		t.Errorf("signed headers = %v, want none for a download", presigned.SignedHeaders)
+	}
+}
+
+func presignedQuery(t *testing.T, presigned *oss.PresignResult) url.Values {
+	t.Helper()
+
+	parsed, err := url.Parse(presigned.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Query()
+}
+
+func TestUploadDownload(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+	headers := map[string]http.Header{}
+	bucket := newTestBucket(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path], headers[r.URL.Path] = body, r.Header
+			w.Header().Set("ETag", `"E1"`)
+		case http.MethodGet:
+			body, ok := objects[r.URL.Path]
+			if !ok {
+				w.Header().Set("Content-Type", "application/xml")
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = io.WriteString(w, `<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`)
+				return
+			}
+			_, _ = w.Write(body)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	})
+	ctx := context.Background()
+
+	_, err := bucket.Upload(ctx, "docs/a.txt", strings.NewReader("hello"),
+		WithContentType("text/plain"), WithMetadata(map[string]string{"owner": "u1"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := headers["/media/docs/a.txt"]
+	if header.Get("Content-Type") != "text/plain" || header.Get("x-oss-meta-owner") != "u1" {
+		t.Errorf("upload headers = %v", header)
+	}
+
+	var buf bytes.Buffer
+	n, err := bucket.Download(ctx, "docs/a.txt", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 || buf.String() != "hello" {
+		t.Errorf("downloaded %d bytes %q", n, buf.String())
+	}
+
+	_, err = bucket.Download(ctx, "docs/missing.txt", &buf)
+	if err == nil || !strings.Contains(err.Error(), "NoSuchKey") {
+		t.Errorf("err = %v, want NoSuchKey", err)
+	}
+}
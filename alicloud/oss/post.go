@@ -0,0 +1,175 @@
+package oss
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	postSignatureVersion = "OSS4-HMAC-SHA256"
+	// defaultCallbackBody is sent to the callback URL when Callback.Body is empty, UploadCallback decodes it.
+	defaultCallbackBody = `{"bucket":${bucket},"object":${object},"etag":${etag},"size":${size},"mimeType":${mimeType}}`
+)
+
+// Callback asks OSS to call back the application after a PostObject upload, see UploadCallbackHandler.
+type Callback struct {
+	URL      string // must be reachable from OSS
+	Host     string // Host header of the callback request, defaults to the host of URL
+	Body     string // defaults to a JSON body with bucket, object, etag, size and mimeType
+	BodyType string // defaults to application/json
+}
+
+type PostPolicyOptions struct {
+	KeyPrefix    string        // uploaded keys must start with it, e.g. uploads/<user id>/
+	MaxSize      int64         // upper bound of the object size in bytes, 0 means no limit
+	ContentTypes []string      // allowed Content-Type form values, empty allows any
+	Expires      time.Duration // validity of the policy, defaults to presign_expires
+	Callback     *Callback
+}
+
+// PostPolicy holds what a browser needs to upload with PostObject: post the Fields along with key,
+// Content-Type and finally file as multipart/form-data to Host.
+type PostPolicy struct {
+	Host       string            `json:"host"`
+	KeyPrefix  string            `json:"key_prefix"`
+	Expiration time.Time         `json:"expiration"`
+	Fields     map[string]string `json:"fields"`
+}
+
+// PostPolicy signs a PostObject policy with signature version 4 so that browsers upload straight to
+// the bucket instead of streaming through the application.
+func (b *BucketClient) PostPolicy(ctx context.Context, options PostPolicyOptions) (*PostPolicy, error) {
+	if b.region == "" {
+		return nil, errors.New("alicloud_oss region is required to sign post policies")
+	}
+
+	cred, err := credentialsProvider.GetCredentials(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get oss credentials: %w", err)
+	}
+
+	expires := options.Expires
+	if expires <= 0 {
+		expires = b.presignExpires
+	}
+
+	now := time.Now().UTC()
+	expiration := now.Add(expires)
+	date := now.Format("20060102")
+	credential := fmt.Sprintf("%s/%s/%s/oss/aliyun_v4_request", cred.AccessKeyID, date, b.region)
+
+	fields := map[string]string{
+		"x-oss-signature-version": postSignatureVersion,
+		"x-oss-credential":        credential,
+		"x-oss-date":              now.Format("20060102T150405Z"),
+		"success_action_status":   "200",
+	}
+	if cred.SecurityToken != "" {
+		fields["x-oss-security-token"] = cred.SecurityToken
+	}
+
+	conditions := []any{
+		map[string]string{"bucket": b.bucket},
+		[]any{"starts-with", "$key", options.KeyPrefix},
+	}
+	for name, value := range fields {
+		conditions = append(conditions, map[string]string{name: value})
+	}
+	if options.MaxSize > 0 {
+		conditions = append(conditions, []any{"content-length-range", 0, options.MaxSize})
+	}
+	if len(options.ContentTypes) > 0 {
+		conditions = append(conditions, []any{"in", "$content-type", options.ContentTypes})
+	}
+
+	if options.Callback != nil {
+		fields["callback"], err = encodeCallback(options.Callback)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	policy, err := json.Marshal(map[string]any{
+		"expiration": expiration.Format("2006-01-02T15:04:05.000Z"),
+		"conditions": conditions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	encodedPolicy := base64.StdEncoding.EncodeToString(policy)
+
+	fields["policy"] = encodedPolicy
+	fields["x-oss-signature"] = hex.EncodeToString(hmacSHA256(signingKey(cred.AccessKeySecret, date, b.region), encodedPolicy))
+
+	return &PostPolicy{
+		Host:       b.host(),
+		KeyPrefix:  options.KeyPrefix,
+		Expiration: expiration,
+		Fields:     fields,
+	}, nil
+}
+
+func encodeCallback(callback *Callback) (string, error) {
+	if callback.URL == "" {
+		return "", errors.New("callback url is required")
+	}
+
+	params := map[string]string{
+		"callbackUrl":      callback.URL,
+		"callbackBody":     callback.Body,
+		"callbackBodyType": callback.BodyType,
+	}
+	if params["callbackBody"] == "" {
+		params["callbackBody"] = defaultCallbackBody
+	}
+	if params["callbackBodyType"] == "" {
+		params["callbackBodyType"] = "application/json"
+	}
+	if callback.Host != "" {
+		params["callbackHost"] = callback.Host
+	}
+
+	data, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// host returns the virtual hosted URL of the bucket, the form action of PostObject.
+func (b *BucketClient) host() string {
+	endpoint := b.endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("oss-%s.aliyuncs.com", b.region)
+	}
+
+	scheme := "https://"
+	if rest, ok := strings.CutPrefix(endpoint, "http://"); ok {
+		scheme, endpoint = "http://", rest
+	}
+	endpoint = strings.TrimPrefix(endpoint, "https://")
+
+	return scheme + b.bucket + "." + strings.TrimRight(endpoint, "/")
+}
+
+func signingKey(secret, date, region string) []byte {
+	key := hmacSHA256([]byte("aliyun_v4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "oss")
+	return hmacSHA256(key, "aliyun_v4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
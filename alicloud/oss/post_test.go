@@ -0,0 +1,147 @@
+package oss
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+)
+
+func TestSigningKey(t *testing.T) {
+	key := signingKey("test-secret", "20250102", "cn-hangzhou")
+	if got := hex.EncodeToString(key); got != "ba2ec6b41f92c7d5df3b71097e1735596db1e72dc608a6eacebb850488cd75bb" {
+		t.Errorf("signing key = %s", got)
+	}
+
+	policy := base64.StdEncoding.EncodeToString([]byte(`{"expiration":"2025-01-02T03:19:05.000Z","conditions":[{"bucket":"media"}]}`))
+	if got := hex.EncodeToString(hmacSHA256(key, policy)); got != "0566d000c32087149b6f7afb3f7148eea4d6751aac882af2894e8ba308c708a7" {
+		t.Errorf("signature = %s", got)
+	}
+}
+
+func useTestCredentials(t *testing.T, token string) {
+	t.Helper()
+
+	provider := credentialsProvider
+	credentialsProvider = credentials.NewStaticCredentialsProvider("test-id", "test-secret", token)
+	t.Cleanup(func() { credentialsProvider = provider })
+}
+
+func TestPostPolicy(t *testing.T) {
+	useTestCredentials(t, "test-token")
+
+	bucket := &BucketClient{bucket: "media", region: "cn-hangzhou", presignExpires: 15 * time.Minute}
+	policy, err := bucket.PostPolicy(context.Background(), PostPolicyOptions{
+		KeyPrefix:    "uploads/u1/",
+		MaxSize:      10 << 20,
+		ContentTypes: []string{"image/png", "image/jpeg"},
+		Callback:     &Callback{URL: "https://example.com/oss/callback"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if policy.Host != "https://media.oss-cn-hangzhou.aliyuncs.com" {
+		t.Errorf("host = %s", policy.Host)
+	}
+	if until := time.Until(policy.Expiration); until < 14*time.Minute || until > 15*time.Minute {
+		t.Errorf("expiration = %s, want presign_expires from now", policy.Expiration)
+	}
+
+	fields := policy.Fields
+	date := fields["x-oss-date"][:8]
+	if fields["x-oss-credential"] != "test-id/"+date+"/cn-hangzhou/oss/aliyun_v4_request" {
+		t.Errorf("credential = %s", fields["x-oss-credential"])
+	}
+	if fields["x-oss-signature-version"] != "OSS4-HMAC-SHA256" || fields["x-oss-security-token"] != "test-token" {
+		t.Errorf("unexpected fields %v", fields)
+	}
+
+	signature := hex.EncodeToString(hmacSHA256(signingKey("test-secret", date, "cn-hangzhou"), fields["policy"]))
+	if fields["x-oss-signature"] != signature {
+		t.Errorf("signature = %s, want %s", fields["x-oss-signature"], signature)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(fields["policy"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var document struct {
+		Expiration string            `json:"expiration"`
+		Conditions []json.RawMessage `json:"conditions"`
+	}
+	err = json.Unmarshal(decoded, &document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if document.Expiration != policy.Expiration.Format("2006-01-02T15:04:05.000Z") {
+		t.Errorf("policy expiration = %s", document.Expiration)
+	}
+
+	conditions := map[string]bool{}
+	for _, condition := range document.Conditions {
+		conditions[string(condition)] = true
+	}
+	for _, want := range []string{
+		`{"bucket":"media"}`,
+		`["starts-with","$key","uploads/u1/"]`,
+		`["content-length-range",0,10485760]`,
+		`["in","$content-type",["image/png","image/jpeg"]]`,
+		`{"x-oss-signature-version":"OSS4-HMAC-SHA256"}`,
+		`{"x-oss-credential":"` + fields["x-oss-credential"] + `"}`,
+		`{"x-oss-date":"` + fields["x-oss-date"] + `"}`,
+		`{"x-oss-security-token":"test-token"}`,
+		`{"success_action_status":"200"}`,
+	} {
+		if !conditions[want] {
+			t.Errorf("policy misses condition %s: %s", want, decoded)
+		}
+	}
+	// the callback isn't a policy condition, OSS checks it through the callback signature
+	if strings.Contains(string(decoded), "callback") {
+		t.Errorf("policy has a callback condition: %s", decoded)
+	}
+
+	callback, err := base64.StdEncoding.DecodeString(fields["callback"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var params map[string]string
+	_ = json.Unmarshal(callback, &params)
+	if params["callbackUrl"] != "https://example.com/oss/callback" || params["callbackBody"] != defaultCallbackBody || params["callbackBodyType"] != "application/json" {
+		t.Errorf("unexpected callback %v", params)
+	}
+}
+
+func TestPostPolicyRequiresRegion(t *testing.T) {
+	useTestCredentials(t, "")
+
+	bucket := &BucketClient{bucket: "media", presignExpires: time.Minute}
+	_, err := bucket.PostPolicy(context.Background(), PostPolicyOptions{})
+	if err == nil {
+		t.Error("want an error without a region")
+	}
+}
+
+func TestBucketHost(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+	}{
+		{"", "https://media.oss-cn-hangzhou.aliyuncs.com"},
+		{"oss-cn-shanghai.aliyuncs.com", "https://media.oss-cn-shanghai.aliyuncs.com"},
+		{"https://oss-cn-shanghai.aliyuncs.com/", "https://media.oss-cn-shanghai.aliyuncs.com"},
+		{"http://oss-cn-shanghai-internal.aliyuncs.com", "http://media.oss-cn-shanghai-internal.aliyuncs.com"},
+	}
+	for _, test := range tests {
+		bucket := &BucketClient{bucket: "media", region: "cn-hangzhou", endpoint: test.endpoint}
+		if got := bucket.host(); got != test.want {
+			t.Errorf("host(%q) = %s, want %s", test.endpoint, got, test.want)
+		}
+	}
+}
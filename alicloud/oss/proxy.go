@@ -0,0 +1,84 @@
+package oss
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/www-xu/spark/log"
+)
+
+// KeyFunc resolves the object key of a download request. It is where the handler authorizes the caller,
+// returning an error rejects the request with 403.
+type KeyFunc func(ctx *gin.Context) (string, error)
+
+var (
+	// forwarded from the client so that ranged and conditional downloads work through the proxy
+	proxyRequestHeaders  = []string{"Range", "If-Match", "If-None-Match", "If-Modified-Since", "If-Unmodified-Since"}
+	proxyResponseHeaders = []string{
+		"Content-Type", "Content-Length", "Content-Range", "Content-Disposition", "Content-Encoding",
+		"Accept-Ranges", "Cache-Control", "ETag", "Last-Modified",
+	}
+)
+
+// ProxyDownload returns a gin handler that signs a download of the key returned by keyFunc and streams
+// the object to the client, so the bucket can stay private and its domain hidden.
+func (b *BucketClient) ProxyDownload(keyFunc KeyFunc, opts ...ObjectOption) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key, err := keyFunc(ctx)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+
+		presigned, err := b.PresignGet(ctx.Request.Context(), key, 0, opts...)
+		if err != nil {
+			log.WithContext(ctx.Request.Context()).WithError(err).WithField("key", key).Error("failed to presign oss download")
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to sign download"})
+			return
+		}
+
+		request, err := http.NewRequestWithContext(ctx.Request.Context(), http.MethodGet, presigned.URL, nil)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to sign download"})
+			return
+		}
+		for _, header := range proxyRequestHeaders {
+			if value := ctx.GetHeader(header); value != "" {
+				request.Header.Set(header, value)
+			}
+		}
+
+		response, err := b.httpClient.Do(request)
+		if err != nil {
+			log.WithContext(ctx.Request.Context()).WithError(err).WithField("key", key).Error("failed to proxy oss download")
+			ctx.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": "failed to download"})
+			return
+		}
+		defer response.Body.Close()
+
+		if response.StatusCode == http.StatusNotFound {
+			ctx.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "object not found"})
+			return
+		}
+		if response.StatusCode >= http.StatusBadRequest && response.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+			body, _ := io.ReadAll(io.LimitReader(response.Body, 4<<10))
+			log.WithContext(ctx.Request.Context()).WithField("key", key).WithField("status", response.StatusCode).
+				WithField("response_body", string(body)).Error("failed to proxy oss download")
+			ctx.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": "failed to download"})
+			return
+		}
+
+		for _, header := range proxyResponseHeaders {
+			if value := response.Header.Get(header); value != "" {
+				ctx.Header(header, value)
+			}
+		}
+		ctx.Status(response.StatusCode)
+
+		_, err = io.Copy(ctx.Writer, response.Body)
+		if err != nil {
+			log.WithContext(ctx.Request.Context()).WithError(err).WithField("key", key).Warn("oss download interrupted")
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package oss
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestProxyDownload(t *testing.T) {
+	bucket := newTestBucket(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("x-oss-signature") == "" {
+			t.Errorf("unsigned download %s", r.URL)
+		}
+		if r.URL.Query().Get("response-content-disposition") != "attachment" {
+			t.Errorf("response-content-disposition = %q", r.URL.Query().Get("response-content-disposition"))
+		}
+
+		switch r.URL.Path {
+		case "/media/files/a.txt":
+			if r.Header.Get("If-None-Match") == `"E1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"E1"`)
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Header().Set("x-oss-request-id", "internal")
+			if r.Header.Get("Range") == "bytes=0-4" {
+				w.Header().Set("Content-Range", "bytes 0-4/11")
+				w.WriteHeader(http.StatusPartialContent)
+				_, _ = io.WriteString(w, "hello")
+				return
+			}
+			if r.Header.Get("Range") == "bytes=20-" {
+				w.Header().Set("Content-Range", "bytes */11")
+				w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+				return
+			}
+			_, _ = io.WriteString(w, "hello world")
+		case "/media/files/missing.txt":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, `<Error><Code>AccessDenied</Code></Error>`)
+		}
+	})
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/files/:name", bucket.ProxyDownload(func(ctx *gin.Context) (string, error) {
+		if ctx.GetHeader("X-User") == "" {
+			return "", errors.New("sign in to download")
+		}
+		return "files/" + ctx.Param("name"), nil
+	}, WithContentDisposition("attachment")))
+
+	download := func(name string, header ...string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/files/"+name, nil)
+		request.Header.Set("X-User", "u1")
+		for i := 0; i+1 < len(header); i += 2 {
+			request.Header.Set(header[i], header[i+1])
+		}
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := download("a.txt")
+	if recorder.Code != http.StatusOK || recorder.Body.String() != "hello world" {
+		t.Errorf("download: %d %q", recorder.Code, recorder.Body)
+	}
+	if recorder.Header().Get("ETag") != `"E1"` || recorder.Header().Get("Content-Type") != "text/plain" || recorder.Header().Get("Accept-Ranges") != "bytes" {
+		t.Errorf("response headers = %v", recorder.Header())
+	}
+	if recorder.Header().Get("x-oss-request-id") != "" {
+		t.Error("OSS headers must not be forwarded")
+	}
+
+	recorder = download("a.txt", "Range", "bytes=0-4")
+	if recorder.Code != http.StatusPartialContent || recorder.Body.String() != "hello" || recorder.Header().Get("Content-Range") != "bytes 0-4/11" {
+		t.Errorf("ranged download: %d %q %v", recorder.Code, recorder.Body, recorder.Header())
+	}
+
+	recorder = download("a.txt", "Range", "bytes=20-")
+	if recorder.Code != http.StatusRequestedRangeNotSatisfiable || recorder.Header().Get("Content-Range") != "bytes */11" {
+		t.Errorf("unsatisfiable range: %d %v", recorder.Code, recorder.Header())
+	}
+
+	if recorder = download("a.txt", "If-None-Match", `"E1"`); recorder.Code != http.StatusNotModified {
+		t.Errorf("conditional download: status = %d, want 304", recorder.Code)
+	}
+	if recorder = download("missing.txt"); recorder.Code != http.StatusNotFound {
+		t.Errorf("missing object: status = %d, want 404", recorder.Code)
+	}
+
+	recorder = download("denied.txt")
+	if recorder.Code != http.StatusBadGateway || strings.Contains(recorder.Body.String(), "AccessDenied") {
+		t.Errorf("failing download: %d %s, want 502 without the OSS error", recorder.Code, recorder.Body)
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/files/a.txt", nil)
+	recorder = httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("unauthorized download: status = %d, want 403", recorder.Code)
+	}
+}
@@ -0,0 +1,231 @@
+package dify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/www-xu/spark/log"
+)
+
+// SendChatMessage sends a message to a chat app in blocking mode. Leave request.ConversationID
+// empty to start a new conversation, the response carries the id to continue it.
+func (c *Client) SendChatMessage(ctx context.Context, apiKey string, request *ChatMessageRequest) (*ChatMessageResponse, error) {
+	body := *request
+	body.ResponseMode = "blocking"
+
+	var response ChatMessageResponse
+	err := c.doJSON(ctx, apiKey, http.MethodPost, "/v1/chat-messages", &body, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// SendCompletionMessage sends a message to a text generator app in blocking mode.
+func (c *Client) SendCompletionMessage(ctx context.Context, apiKey string, request *CompletionMessageRequest) (*CompletionMessageResponse, error) {
+	body := *request
+	body.ResponseMode = "blocking"
+
+	var response CompletionMessageResponse
+	err := c.doJSON(ctx, apiKey, http.MethodPost, "/v1/completion-messages", &body, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// UploadFile uploads a file to be referenced by workflow inputs or message files with its id.
+func (c *Client) UploadFile(ctx context.Context, apiKey, userID, filename string, file io.Reader) (*UploadedFile, error) {
+	var buffer bytes.Buffer
+	writer := multipart.NewWriter(&buffer)
+
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.Copy(part, file)
+	if err != nil {
+		return nil, err
+	}
+	err = writer.WriteField("user", userID)
+	if err != nil {
+		return nil, err
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.host+"/v1/files/upload", &buffer)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+
+	var response UploadedFile
+	err = c.do(ctx, apiKey, request, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// StopWorkflowTask stops a streaming workflow run by the task id from its events.
+func (c *Client) StopWorkflowTask(ctx context.Context, apiKey, taskID, userID string) error {
+	return c.stopTask(ctx, apiKey, "/v1/workflows/tasks/"+url.PathEscape(taskID)+"/stop", userID)
+}
+
+// StopChatMessage stops a streaming chat message by its task id.
+func (c *Client) StopChatMessage(ctx context.Context, apiKey, taskID, userID string) error {
+	return c.stopTask(ctx, apiKey, "/v1/chat-messages/"+url.PathEscape(taskID)+"/stop", userID)
+}
+
+// StopCompletionMessage stops a streaming completion message by its task id.
+func (c *Client) StopCompletionMessage(ctx context.Context, apiKey, taskID, userID string) error {
+	return c.stopTask(ctx, apiKey, "/v1/completion-messages/"+url.PathEscape(taskID)+"/stop", userID)
+}
+
+func (c *Client) stopTask(ctx context.Context, apiKey, path, userID string) error {
+	var response ResultResponse
+	return c.doJSON(ctx, apiKey, http.MethodPost, path, map[string]string{"user": userID}, &response)
+}
+
+// GetWorkflowRun returns the details of a workflow run, including its inputs and outputs.
+func (c *Client) GetWorkflowRun(ctx context.Context, apiKey, workflowRunID string) (*WorkflowRun, error) {
+	var response WorkflowRun
+	err := c.doJSON(ctx, apiKey, http.MethodGet, "/v1/workflows/run/"+url.PathEscape(workflowRunID), nil, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// ListWorkflowLogs returns a page of workflow run logs, newest first.
+func (c *Client) ListWorkflowLogs(ctx context.Context, apiKey string, request *WorkflowLogsRequest) (*WorkflowLogsResponse, error) {
+	query := url.Values{}
+	if request.Keyword != "" {
+		query.Set("keyword", request.Keyword)
+	}
+	if request.Status != "" {
+		query.Set("status", request.Status)
+	}
+	if request.Page > 0 {
+		query.Set("page", strconv.Itoa(request.Page))
+	}
+	if request.Limit > 0 {
+		query.Set("limit", strconv.Itoa(request.Limit))
+	}
+
+	var response WorkflowLogsResponse
+	err := c.doJSON(ctx, apiKey, http.MethodGet, "/v1/workflows/logs?"+query.Encode(), nil, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// SendMessageFeedback rates a message, an empty rating revokes the previous feedback.
+func (c *Client) SendMessageFeedback(ctx context.Context, apiKey, messageID string, request *MessageFeedbackRequest) error {
+	body := map[string]any{
+		"user":    request.User,
+		"content": request.Content,
+		"rating":  nil,
+	}
+	if request.Rating != "" {
+		body["rating"] = request.Rating
+	}
+
+	var response ResultResponse
+	return c.doJSON(ctx, apiKey, http.MethodPost, "/v1/messages/"+url.PathEscape(messageID)+"/feedbacks", body, &response)
+}
+
+// ListConversations returns the conversations of a user, use LastID of the previous page to paginate.
+func (c *Client) ListConversations(ctx context.Context, apiKey string, request *ConversationsRequest) (*ConversationsResponse, error) {
+	query := url.Values{}
+	query.Set("user", request.User)
+	if request.LastID != "" {
+		query.Set("last_id", request.LastID)
+	}
+	if request.Limit > 0 {
+		query.Set("limit", strconv.Itoa(request.Limit))
+	}
+	if request.SortBy != "" {
+		query.Set("sort_by", request.SortBy)
+	}
+
+	var response ConversationsResponse
+	err := c.doJSON(ctx, apiKey, http.MethodGet, "/v1/conversations?"+query.Encode(), nil, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+func (c *Client) doJSON(ctx context.Context, apiKey, method, path string, requestBody, response any) error {
+	var body io.Reader
+	if requestBody != nil {
+		requestBytes, err := json.Marshal(requestBody)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(requestBytes)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, c.host+path, body)
+	if err != nil {
+		return err
+	}
+	if requestBody != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	return c.do(ctx, apiKey, request, response)
+}
+
+func (c *Client) do(ctx context.Context, apiKey string, request *http.Request, response any) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	request = request.WithContext(ctx)
+
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+
+	defer func() {
+		if err != nil {
+			log.WithContext(ctx).WithError(err).WithFields(map[string]interface{}{
+				"method": request.Method,
+				"path":   request.URL.Path,
+			}).Error("failed to call dify")
+		}
+	}()
+
+	rawResponse, err := c.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer rawResponse.Body.Close()
+
+	responseBody, err := io.ReadAll(rawResponse.Body)
+	if err != nil {
+		return err
+	}
+
+	if rawResponse.StatusCode != http.StatusOK {
+		return fmt.Errorf("[%s] | %s", rawResponse.Status, string(responseBody))
+	}
+
+	return json.Unmarshal(responseBody, response)
+}
@@ -0,0 +1,371 @@
+package dify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testAPIKey = "app-test"
+
+// newTestClient returns a client of a dify server handled by handler, which also checks the api key.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testAPIKey {
+			t.Errorf("Authorization = %q, want Bearer %s", r.Header.Get("Authorization"), testAPIKey)
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return NewClient(server.URL)
+}
+
+func expectRequest(t *testing.T, r *http.Request, method, path string) {
+	t.Helper()
+
+	if r.Method != method || r.URL.Path != path {
+		t.Errorf("request = %s %s, want %s %s", r.Method, r.URL.Path, method, path)
+	}
+}
+
+func decodeBody(t *testing.T, r *http.Request) map[string]any {
+	t.Helper()
+
+	var body map[string]any
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		t.Fatalf("decode request body: %v", err)
+	}
+
+	return body
+}
+
+func writeJSON(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, body)
+}
+
+func TestSendChatMessage(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		expectRequest(t, r, http.MethodPost, "/v1/chat-messages")
+
+		body := decodeBody(t, r)
+		if body["response_mode"] != "blocking" || body["query"] != "hi" || body["user"] != "u1" || body["conversation_id"] != "c1" {
+			t.Errorf("unexpected body %v", body)
+		}
+
+		writeJSON(w, http.StatusOK, `{"event":"message","message_id":"m1","conversation_id":"c1","answer":"hello","metadata":{"usage":{"total_tokens":7}}}`)
+	})
+
+	response, err := client.SendChatMessage(context.Background(), testAPIKey, &ChatMessageRequest{
+		Query:          "hi",
+		User:           "u1",
+		ConversationID: "c1",
+		ResponseMode:   "streaming",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.MessageID != "m1" || response.Answer != "hello" || response.Metadata.Usage.TotalTokens != 7 {
+		t.Errorf("unexpected response %+v", response)
+	}
+}
+
+func TestSendChatMessageError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusBadRequest, `{"code":"invalid_param","message":"query is required"}`)
+	})
+
+	_, err := client.SendChatMessage(context.Background(), testAPIKey, &ChatMessageRequest{User: "u1"})
+	if err == nil || !strings.Contains(err.Error(), "400") || !strings.Contains(err.Error(), "query is required") {
+		t.Fatalf("err = %v, want the status and body of the 400 response", err)
+	}
+}
+
+func TestSendCompletionMessage(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		expectRequest(t, r, http.MethodPost, "/v1/completion-messages")
+
+		body := decodeBody(t, r)
+		inputs, _ := body["inputs"].(map[string]any)
+		if body["response_mode"] != "blocking" || inputs["topic"] != "go" {
+			t.Errorf("unexpected body %v", body)
+		}
+
+		writeJSON(w, http.StatusOK, `{"message_id":"m2","answer":"done"}`)
+	})
+
+	response, err := client.SendCompletionMessage(context.Background(), testAPIKey, &CompletionMessageRequest{
+		Inputs: map[string]any{"topic": "go"},
+		User:   "u1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.MessageID != "m2" || response.Answer != "done" {
+		t.Errorf("unexpected response %+v", response)
+	}
+}
+
+func TestSendCompletionMessageInvalidResponse(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `not json`)
+	})
+
+	_, err := client.SendCompletionMessage(context.Background(), testAPIKey, &CompletionMessageRequest{User: "u1"})
+	if err == nil {
+		t.Fatal("expected an error for an invalid response body")
+	}
+}
+
+func TestUploadFile(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		expectRequest(t, r, http.MethodPost, "/v1/files/upload")
+
+		err := r.ParseMultipartForm(1 << 20)
+		if err != nil {
+			t.Fatalf("parse multipart form: %v", err)
+		}
+		if r.FormValue("user") != "u1" {
+			t.Errorf("user = %q, want u1", r.FormValue("user"))
+		}
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Fatalf("form file: %v", err)
+		}
+		defer file.Close()
+		content, _ := io.ReadAll(file)
+		if header.Filename != "a.txt" || string(content) != "hello" {
+			t.Errorf("file = %s %q, want a.txt hello", header.Filename, content)
+		}
+
+		writeJSON(w, http.StatusOK, `{"id":"f1","name":"a.txt","size":5}`)
+	})
+
+	file, err := client.UploadFile(context.Background(), testAPIKey, "u1", "a.txt", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.ID != "f1" || file.Size != 5 {
+		t.Errorf("unexpected file %+v", file)
+	}
+}
+
+func TestUploadFileError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusRequestEntityTooLarge, `{"code":"file_too_large"}`)
+	})
+
+	_, err := client.UploadFile(context.Background(), testAPIKey, "u1", "a.txt", strings.NewReader("hello"))
+	if err == nil || !strings.Contains(err.Error(), "file_too_large") {
+		t.Fatalf("err = %v, want file_too_large", err)
+	}
+}
+
+func TestStopTasks(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		stop func(client *Client) error
+	}{
+		{
+			name: "workflow",
+			path: "/v1/workflows/tasks/t1/stop",
+			stop: func(client *Client) error {
+				return client.StopWorkflowTask(context.Background(), testAPIKey, "t1", "u1")
+			},
+		},
+		{
+			name: "chat",
+			path: "/v1/chat-messages/t1/stop",
+			stop: func(client *Client) error {
+				return client.StopChatMessage(context.Background(), testAPIKey, "t1", "u1")
+			},
+		},
+		{
+			name: "completion",
+			path: "/v1/completion-messages/t1/stop",
+			stop: func(client *Client) error {
+				return client.StopCompletionMessage(context.Background(), testAPIKey, "t1", "u1")
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				expectRequest(t, r, http.MethodPost, test.path)
+				if body := decodeBody(t, r); body["user"] != "u1" {
+					t.Errorf("unexpected body %v", body)
+				}
+				writeJSON(w, http.StatusOK, `{"result":"success"}`)
+			})
+
+			err := test.stop(client)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestStopTaskError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusNotFound, `{"code":"not_found"}`)
+	})
+
+	err := client.StopWorkflowTask(context.Background(), testAPIKey, "t1", "u1")
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("err = %v, want 404", err)
+	}
+}
+
+func TestGetWorkflowRun(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		expectRequest(t, r, http.MethodGet, "/v1/workflows/run/r1")
+		writeJSON(w, http.StatusOK, `{"id":"r1","status":"succeeded","outputs":{"text":"ok"},"total_steps":3}`)
+	})
+
+	run, err := client.GetWorkflowRun(context.Background(), testAPIKey, "r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.ID != "r1" || run.Status != "succeeded" || run.Outputs["text"] != "ok" || run.TotalSteps != 3 {
+		t.Errorf("unexpected run %+v", run)
+	}
+}
+
+func TestGetWorkflowRunNotFound(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusNotFound, `{"code":"not_found","message":"Workflow run not found"}`)
+	})
+
+	_, err := client.GetWorkflowRun(context.Background(), testAPIKey, "missing")
+	if err == nil || !strings.Contains(err.Error(), "Workflow run not found") {
+		t.Fatalf("err = %v, want not found", err)
+	}
+}
+
+func TestListWorkflowLogs(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		expectRequest(t, r, http.MethodGet, "/v1/workflows/logs")
+
+		query := r.URL.Query()
+		if query.Get("status") != "failed" || query.Get("page") != "2" || query.Get("limit") != "10" || query.Has("keyword") {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+
+		writeJSON(w, http.StatusOK, `{"page":2,"limit":10,"total":11,"has_more":false,"data":[{"id":"l1","workflow_run":{"id":"r1","status":"failed"}}]}`)
+	})
+
+	logs, err := client.ListWorkflowLogs(context.Background(), testAPIKey, &WorkflowLogsRequest{Status: "failed", Page: 2, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if logs.Total != 11 || len(logs.Data) != 1 || logs.Data[0].WorkflowRun.ID != "r1" {
+		t.Errorf("unexpected logs %+v", logs)
+	}
+}
+
+func TestListWorkflowLogsError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusUnauthorized, `{"code":"unauthorized"}`)
+	})
+
+	_, err := client.ListWorkflowLogs(context.Background(), testAPIKey, &WorkflowLogsRequest{})
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("err = %v, want 401", err)
+	}
+}
+
+func TestSendMessageFeedback(t *testing.T) {
+	tests := []struct {
+		name   string
+		rating string
+		want   any
+	}{
+		{name: "like", rating: "like", want: "like"},
+		{name: "revoke", rating: "", want: nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				expectRequest(t, r, http.MethodPost, "/v1/messages/m1/feedbacks")
+
+				body := decodeBody(t, r)
+				rating, ok := body["rating"]
+				if !ok || rating != test.want || body["user"] != "u1" {
+					t.Errorf("unexpected body %v", body)
+				}
+
+				writeJSON(w, http.StatusOK, `{"result":"success"}`)
+			})
+
+			err := client.SendMessageFeedback(context.Background(), testAPIKey, "m1", &MessageFeedbackRequest{Rating: test.rating, User: "u1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestSendMessageFeedbackError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusInternalServerError, `{"code":"internal_server_error"}`)
+	})
+
+	err := client.SendMessageFeedback(context.Background(), testAPIKey, "m1", &MessageFeedbackRequest{Rating: "like", User: "u1"})
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Fatalf("err = %v, want 500", err)
+	}
+}
+
+func TestListConversations(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		expectRequest(t, r, http.MethodGet, "/v1/conversations")
+
+		query := r.URL.Query()
+		if query.Get("user") != "u1" || query.Get("last_id") != "c0" || query.Get("limit") != "20" || query.Get("sort_by") != "-updated_at" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+
+		writeJSON(w, http.StatusOK, `{"limit":20,"has_more":true,"data":[{"id":"c1","name":"first"}]}`)
+	})
+
+	conversations, err := client.ListConversations(context.Background(), testAPIKey, &ConversationsRequest{
+		User:   "u1",
+		LastID: "c0",
+		Limit:  20,
+		SortBy: "-updated_at",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !conversations.HasMore || len(conversations.Data) != 1 || conversations.Data[0].ID != "c1" {
+		t.Errorf("unexpected conversations %+v", conversations)
+	}
+}
+
+func TestListConversationsCanceled(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, `{"data":[]}`)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.ListConversations(ctx, testAPIKey, &ConversationsRequest{User: "u1"})
+	if err == nil {
+		t.Fatal("expected an error for a canceled context")
+	}
+}
@@ -0,0 +1,150 @@
+package dify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// SecretProvider loads app keys referenced by api_key_secret, e.g. from a vault or a cloud secrets manager.
+type SecretProvider interface {
+	GetSecret(ctx context.Context, name string) (string, error)
+}
+
+var secretProvider SecretProvider
+
+// SetSecretProvider sets the provider used to resolve api_key_secret, it must be called before spark.Init.
+func SetSecretProvider(provider SecretProvider) {
+	secretProvider = provider
+}
+
+// AppClient calls a dify app declared in dify.apps with its own key, host and timeout.
+type AppClient struct {
+	name   string
+	apiKey string
+	client *Client
+}
+
+func (c *Component) newApps(ctx context.Context) error {
+	c.apps = make(map[string]*AppClient, len(c.config.Apps))
+
+	for name, appConfig := range c.config.Apps {
+		apiKey, err := resolveAPIKey(ctx, appConfig)
+		if err != nil {
+			return fmt.Errorf("dify app %s: %w", name, err)
+		}
+
+		host := appConfig.Host
+		if host == "" {
+			host = c.config.Host
+		}
+
+		client := NewClient(host)
+		client.httpClient = c.httpClient
+		if appConfig.Timeout > 0 {
+			client.timeout = appConfig.Timeout
+		} else if c.config.Timeout > 0 {
+			client.timeout = c.config.Timeout
+		}
+
+		c.apps[name] = &AppClient{
+			name:   name,
+			apiKey: apiKey,
+			client: client,
+		}
+	}
+
+	return nil
+}
+
+func resolveAPIKey(ctx context.Context, config AppConfig) (string, error) {
+	switch {
+	case config.APIKeySecret != "":
+		if secretProvider == nil {
+			return "", errors.New("api_key_secret is set but no secret provider is registered")
+		}
+		return secretProvider.GetSecret(ctx, config.APIKeySecret)
+	case config.APIKeyEnv != "":
+		apiKey, ok := os.LookupEnv(config.APIKeyEnv)
+		if !ok || apiKey == "" {
+			return "", fmt.Errorf("environment variable %s isn't set", config.APIKeyEnv)
+		}
+		return apiKey, nil
+	case config.APIKey != "":
+		return config.APIKey, nil
+	default:
+		return "", errors.New("one of api_key, api_key_env and api_key_secret is required")
+	}
+}
+
+// App returns the client of the app declared as dify.apps.<name>.
+func App(ctx context.Context, name string) (*AppClient, error) {
+	return instance.App(ctx, name)
+}
+
+func (c *Component) App(ctx context.Context, name string) (*AppClient, error) {
+	app, ok := c.apps[name]
+	if !ok {
+		return nil, errors.New("dify app isn't declared: " + name)
+	}
+
+	return app, nil
+}
+
+func (a *AppClient) Name() string {
+	return a.name
+}
+
+func (a *AppClient) InvokeWorkflow(ctx context.Context, userID string, inputs map[string]interface{}) (*InvokeWorkflowResponse, error) {
+	return a.client.InvokeWorkflow(ctx, a.apiKey, userID, inputs)
+}
+
+func (a *AppClient) StreamWorkflow(ctx context.Context, userID string, inputs map[string]interface{}) (*WorkflowStream, error) {
+	return a.client.StreamWorkflow(ctx, a.apiKey, userID, inputs)
+}
+
+func (a *AppClient) SendChatMessage(ctx context.Context, request *ChatMessageRequest) (*ChatMessageResponse, error) {
+	return a.client.SendChatMessage(ctx, a.apiKey, request)
+}
+
+func (a *AppClient) StreamChatMessage(ctx context.Context, request *ChatMessageRequest) (*ChatMessageStream, error) {
+	return a.client.StreamChatMessage(ctx, a.apiKey, request)
+}
+
+func (a *AppClient) SendCompletionMessage(ctx context.Context, request *CompletionMessageRequest) (*CompletionMessageResponse, error) {
+	return a.client.SendCompletionMessage(ctx, a.apiKey, request)
+}
+
+func (a *AppClient) UploadFile(ctx context.Context, userID, filename string, file io.Reader) (*UploadedFile, error) {
+	return a.client.UploadFile(ctx, a.apiKey, userID, filename, file)
+}
+
+func (a *AppClient) StopWorkflowTask(ctx context.Context, taskID, userID string) error {
+	return a.client.StopWorkflowTask(ctx, a.apiKey, taskID, userID)
+}
+
+func (a *AppClient) StopChatMessage(ctx context.Context, taskID, userID string) error {
+	return a.client.StopChatMessage(ctx, a.apiKey, taskID, userID)
+}
+
+func (a *AppClient) StopCompletionMessage(ctx context.Context, taskID, userID string) error {
+	return a.client.StopCompletionMessage(ctx, a.apiKey, taskID, userID)
+}
+
+func (a *AppClient) GetWorkflowRun(ctx context.Context, workflowRunID string) (*WorkflowRun, error) {
+	return a.client.GetWorkflowRun(ctx, a.apiKey, workflowRunID)
+}
+
+func (a *AppClient) ListWorkflowLogs(ctx context.Context, request *WorkflowLogsRequest) (*WorkflowLogsResponse, error) {
+	return a.client.ListWorkflowLogs(ctx, a.apiKey, request)
+}
+
+func (a *AppClient) SendMessageFeedback(ctx context.Context, messageID string, request *MessageFeedbackRequest) error {
+	return a.client.SendMessageFeedback(ctx, a.apiKey, messageID, request)
+}
+
+func (a *AppClient) ListConversations(ctx context.Context, request *ConversationsRequest) (*ConversationsResponse, error) {
+	return a.client.ListConversations(ctx, a.apiKey, request)
+}
@@ -0,0 +1,31 @@
+package dify
+
+import (
+	"context"
+	"testing"
+)
+
+func TestResolveAPIKey(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := resolveAPIKey(ctx, AppConfig{APIKey: "ignored", APIKeyEnv: "DIFY_TEST_API_KEY"})
+	if err == nil {
+		t.Error("want an error while the environment variable isn't set")
+	}
+
+	t.Setenv("DIFY_TEST_API_KEY", "app-env")
+	apiKey, err := resolveAPIKey(ctx, AppConfig{APIKey: "ignored", APIKeyEnv: "DIFY_TEST_API_KEY"})
+	if err != nil || apiKey != "app-env" {
+		t.Errorf("api key = %q, %v, want the environment variable", apiKey, err)
+	}
+
+	apiKey, err = resolveAPIKey(ctx, AppConfig{APIKey: "app-plain"})
+	if err != nil || apiKey != "app-plain" {
+		t.Errorf("api key = %q, %v, want api_key", apiKey, err)
+	}
+
+	_, err = resolveAPIKey(ctx, AppConfig{})
+	if err == nil {
+		t.Error("want an error without a key")
+	}
+}
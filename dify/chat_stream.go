@@ -0,0 +1,54 @@
+package dify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/www-xu/spark/httpclient"
+	"github.com/www-xu/spark/log"
+)
+
+// ChatMessageStream reads the events of a streaming chat message. Answers arrive in pieces with
+// message events, message_end carries the usage and ends the stream.
+type ChatMessageStream = Stream[ChatStreamEvent]
+
+// StreamChatMessage sends a message to a chat app with response_mode streaming. Like StreamWorkflow
+// the message is bound to ctx instead of the client timeout. Callers must Close the stream.
+func (c *Client) StreamChatMessage(ctx context.Context, apiKey string, request *ChatMessageRequest) (*ChatMessageStream, error) {
+	body := *request
+	body.ResponseMode = "streaming"
+
+	requestBytes, err := json.Marshal(&body)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRequest, err := http.NewRequestWithContext(httpclient.WithTimeout(ctx, 0), http.MethodPost, c.host+"/v1/chat-messages", bytes.NewReader(requestBytes))
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+
+	responseBody, err := c.openStream(httpRequest)
+	if err != nil {
+		log.WithContext(ctx).WithError(err).WithField("conversation_id", request.ConversationID).Error("failed to stream chat message")
+		return nil, err
+	}
+
+	return newStream(ctx, responseBody, EventMessageEnd, parseChatStreamEvent), nil
+}
+
+func parseChatStreamEvent(data []byte) (*ChatStreamEvent, string, error) {
+	var event ChatStreamEvent
+	err := json.Unmarshal(data, &event)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid dify stream event %s: %w", string(data), err)
+	}
+	event.Raw = data
+
+	return &event, event.Event, nil
+}
@@ -0,0 +1,102 @@
+package dify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func newChatStreamClient(t *testing.T, events string) *Client {
+	t.Helper()
+
+	return newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		expectRequest(t, r, http.MethodPost, "/v1/chat-messages")
+		if body := decodeBody(t, r); body["response_mode"] != "streaming" {
+			t.Errorf("response_mode = %v, want streaming", body["response_mode"])
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, events)
+	})
+}
+
+func TestStreamChatMessage(t *testing.T) {
+	client := newChatStreamClient(t, "event: ping\n\n"+
+		`data: {"event":"message","task_id":"t1","message_id":"m1","conversation_id":"c1","answer":"Hel"}`+"\n\n"+
+		`data: {"event":"message","task_id":"t1","message_id":"m1","conversation_id":"c1","answer":"lo"}`+"\n\n"+
+		`data: {"event":"message_end","task_id":"t1","message_id":"m1","conversation_id":"c1","metadata":{"usage":{"total_tokens":5}}}`+"\n\n")
+
+	stream, err := client.StreamChatMessage(context.Background(), testAPIKey, &ChatMessageRequest{Query: "hi", User: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	var answer strings.Builder
+	var end *ChatStreamEvent
+	for {
+		event, err := stream.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		switch event.Event {
+		case EventMessage:
+			answer.WriteString(event.Answer)
+		case EventMessageEnd:
+			end = event
+		}
+	}
+
+	if answer.String() != "Hello" {
+		t.Errorf("answer = %q, want Hello", answer.String())
+	}
+	if end == nil || end.ConversationID != "c1" || end.Metadata == nil || end.Metadata.Usage.TotalTokens != 5 {
+		t.Errorf("unexpected message_end %+v", end)
+	}
+}
+
+func TestStreamChatMessageErrorEvent(t *testing.T) {
+	client := newChatStreamClient(t,
+		`data: {"event":"message","task_id":"t1","message_id":"m1","answer":"Hel"}`+"\n\n"+
+			`data: {"event":"error","task_id":"t1","message_id":"m1","status":400,"code":"completion_request_error","message":"quota exceeded"}`+"\n\n")
+
+	stream, err := client.StreamChatMessage(context.Background(), testAPIKey, &ChatMessageRequest{Query: "hi", User: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	_, err = stream.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = stream.Next()
+	var streamError *StreamError
+	if !errors.As(err, &streamError) || streamError.Code != "completion_request_error" || streamError.MessageID != "m1" {
+		t.Fatalf("err = %v, want the error event", err)
+	}
+
+	_, err = stream.Next()
+	if !errors.Is(err, io.EOF) {
+		t.Fatalf("err = %v after the error event, want io.EOF", err)
+	}
+}
+
+func TestStreamChatMessageStatusError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusNotFound, `{"code":"not_found","message":"Conversation Not Exists."}`)
+	})
+
+	_, err := client.StreamChatMessage(context.Background(), testAPIKey, &ChatMessageRequest{Query: "hi", User: "u1", ConversationID: "missing"})
+	if err == nil || !strings.Contains(err.Error(), "Conversation Not Exists.") {
+		t.Fatalf("err = %v, want the 404 body", err)
+	}
+}
@@ -10,18 +10,23 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/www-xu/spark/httpclient"
 	"github.com/www-xu/spark/log"
 )
 
+const defaultTimeout = 3 * time.Minute
+
 type Client struct {
 	host       string
+	timeout    time.Duration
 	httpClient *http.Client
 }
 
 func NewClient(host string) *Client {
 	return &Client{
 		host:       host,
-		httpClient: &http.Client{},
+		timeout:    defaultTimeout,
+		httpClient: httpclient.New(httpclient.Config{Name: "dify"}),
 	}
 }
 
@@ -31,21 +36,14 @@ func (c *Client) InvokeWorkflow(ctx context.Context, workflowId, userID string,
 		"inputs":        inputs,
 		"user":          userID,
 	}
-	requestBytes, err := json.Marshal(requestBody)
-	if err != nil {
-		return nil, err
-	}
 
-	ctx, cancel := context.WithTimeout(ctx, 3*time.Minute)
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
-	request, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s%s", c.host, "/v1/workflows/run"), bytes.NewReader(requestBytes))
+	request, err := c.newRunRequest(ctx, workflowId, userID, inputs, "blocking")
 	if err != nil {
 		return nil, err
 	}
-	request.Header.Set("Accept", "application/json")
-	request.Header.Set("Content-Type", "application/json")
-	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", workflowId))
 
 	defer func() {
 		if err != nil {
@@ -60,6 +58,7 @@ func (c *Client) InvokeWorkflow(ctx context.Context, workflowId, userID string,
 	if err != nil {
 		return nil, err
 	}
+	defer rawResponse.Body.Close()
 
 	responseBody, err := io.ReadAll(rawResponse.Body)
 	if err != nil {
@@ -84,3 +83,24 @@ func (c *Client) InvokeWorkflow(ctx context.Context, workflowId, userID string,
 
 	return response, nil
 }
+
+func (c *Client) newRunRequest(ctx context.Context, workflowId, userID string, inputs map[string]interface{}, responseMode string) (*http.Request, error) {
+	requestBytes, err := json.Marshal(map[string]interface{}{
+		"response_mode": responseMode,
+		"inputs":        inputs,
+		"user":          userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s%s", c.host, "/v1/workflows/run"), bytes.NewReader(requestBytes))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", workflowId))
+
+	return request, nil
+}
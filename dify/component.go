@@ -3,18 +3,26 @@ package dify
 import (
 	"context"
 	"errors"
+	"net/http"
 
 	"github.com/www-xu/spark"
+	"github.com/www-xu/spark/httpclient"
+	"github.com/www-xu/spark/webhook"
 )
 
 type Component struct {
-	ctx      *spark.ApplicationContext
-	config   *Config
-	instance *Client
+	ctx        *spark.ApplicationContext
+	config     *Config
+	instance   *Client
+	apps       map[string]*AppClient
+	httpClient *http.Client
+	webhook    *webhook.Receiver
 }
 
 func NewComponent() *Component {
-	return &Component{}
+	return &Component{
+		webhook: newReceiver(),
+	}
 }
 
 var instance *Component
@@ -36,7 +44,26 @@ func (c *Component) Instantiate() error {
 		return errors.New("dify config isn't found")
 	}
 
+	if c.config.HTTP.Name == "" {
+		c.config.HTTP.Name = "dify"
+	}
+	c.httpClient = httpclient.New(c.config.HTTP)
+
 	c.instance = NewClient(c.config.Host)
+	c.instance.httpClient = c.httpClient
+	if c.config.Timeout > 0 {
+		c.instance.timeout = c.config.Timeout
+	}
+
+	err = c.newApps(context.Background())
+	if err != nil {
+		return err
+	}
+
+	err = c.webhook.Configure(c.config.Webhook)
+	if err != nil {
+		return err
+	}
 
 	return nil
 }
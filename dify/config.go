@@ -1,5 +1,24 @@
 package dify
 
+import (
+	"time"
+
+	"github.com/www-xu/spark/httpclient"
+)
+
+type AppConfig struct {
+	// APIKey is the app key in plain text, prefer APIKeyEnv or APIKeySecret to keep keys out of config files.
+	APIKey       string        `mapstructure:"api_key"`
+	APIKeyEnv    string        `mapstructure:"api_key_env"`    // name of the environment variable holding the key
+	APIKeySecret string        `mapstructure:"api_key_secret"` // name of the key in the SecretProvider
+	Host         string        `mapstructure:"host"`           // defaults to dify.host
+	Timeout      time.Duration `mapstructure:"timeout"`        // defaults to dify.timeout
+}
+
 type Config struct {
-	Host string `mapstructure:"host"`
+	Host    string               `mapstructure:"host"`
+	Timeout time.Duration        `mapstructure:"timeout"` // timeout of blocking calls, defaults to 3 minutes
+	Apps    map[string]AppConfig `mapstructure:"apps"`    // key is the app name used by App
+	HTTP    httpclient.Config    `mapstructure:"http"`    // transport shared by all apps
+	Webhook WebhookConfig        `mapstructure:"webhook"` // receiver of callbacks from workflows, see Webhook
 }
@@ -1,5 +1,10 @@
 package dify
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 type InvokeWorkflowResponse struct {
 	WorkflowRunID string       `json:"workflow_run_id"`
 	TaskID        string       `json:"task_id"`
@@ -18,3 +23,254 @@ type WorkflowData struct {
 	CreatedAt   int64          `json:"created_at"`
 	FinishedAt  int64          `json:"finished_at"`
 }
+
+const (
+	EventWorkflowStarted  = "workflow_started"
+	EventNodeStarted      = "node_started"
+	EventNodeFinished     = "node_finished"
+	EventTextChunk        = "text_chunk"
+	EventWorkflowFinished = "workflow_finished"
+	EventError            = "error"
+	EventPing             = "ping"
+
+	EventMessage    = "message"
+	EventMessageEnd = "message_end"
+)
+
+// StreamEvent is one server-sent event of a streaming workflow run. Depending on Event
+// one of Workflow, Node or TextChunk is set, Raw keeps the event as received.
+type StreamEvent struct {
+	Event         string          `json:"event"`
+	TaskID        string          `json:"task_id"`
+	WorkflowRunID string          `json:"workflow_run_id"`
+	Workflow      *WorkflowData   `json:"-"`
+	Node          *NodeData       `json:"-"`
+	TextChunk     *TextChunkData  `json:"-"`
+	Raw           json.RawMessage `json:"-"`
+}
+
+// ChatStreamEvent is one server-sent event of a streaming chat message. Message events carry
+// a piece of Answer, message_end carries Metadata. Other events, e.g. the workflow and node events
+// of advanced chat apps, are only available in Raw.
+type ChatStreamEvent struct {
+	Event          string           `json:"event"`
+	TaskID         string           `json:"task_id"`
+	MessageID      string           `json:"message_id"`
+	ConversationID string           `json:"conversation_id"`
+	Answer         string           `json:"answer"`
+	Metadata       *MessageMetadata `json:"metadata"`
+	CreatedAt      int64            `json:"created_at"`
+	Raw            json.RawMessage  `json:"-"`
+}
+
+type NodeData struct {
+	ID                string         `json:"id"`
+	NodeID            string         `json:"node_id"`
+	NodeType          string         `json:"node_type"`
+	Title             string         `json:"title"`
+	Index             int            `json:"index"`
+	PredecessorNodeID string         `json:"predecessor_node_id"`
+	Inputs            map[string]any `json:"inputs"`
+	ProcessData       map[string]any `json:"process_data"`
+	Outputs           map[string]any `json:"outputs"`
+	Status            string         `json:"status"`
+	Error             *string        `json:"error"`
+	ElapsedTime       float64        `json:"elapsed_time"`
+	CreatedAt         int64          `json:"created_at"`
+	FinishedAt        int64          `json:"finished_at"`
+}
+
+type TextChunkData struct {
+	Text                 string   `json:"text"`
+	FromVariableSelector []string `json:"from_variable_selector"`
+}
+
+// StreamError is the payload of an error event, returned as the error of Stream.Next.
+type StreamError struct {
+	TaskID        string `json:"task_id"`
+	WorkflowRunID string `json:"workflow_run_id"`
+	MessageID     string `json:"message_id"`
+	Status        int    `json:"status"`
+	Code          string `json:"code"`
+	Message       string `json:"message"`
+}
+
+func (e *StreamError) Error() string {
+	return fmt.Sprintf("dify stream error [%d] %s: %s", e.Status, e.Code, e.Message)
+}
+
+// InputFile references a file in chat or completion messages, either uploaded with UploadFile
+// (TransferMethod local_file and UploadFileID) or by URL (TransferMethod remote_url).
+type InputFile struct {
+	Type           string `json:"type"` // document, image, audio, video or custom
+	TransferMethod string `json:"transfer_method"`
+	URL            string `json:"url,omitempty"`
+	UploadFileID   string `json:"upload_file_id,omitempty"`
+}
+
+type ChatMessageRequest struct {
+	Query            string         `json:"query"`
+	Inputs           map[string]any `json:"inputs"`
+	ResponseMode     string         `json:"response_mode"`
+	User             string         `json:"user"`
+	ConversationID   string         `json:"conversation_id,omitempty"`
+	Files            []InputFile    `json:"files,omitempty"`
+	AutoGenerateName *bool          `json:"auto_generate_name,omitempty"`
+}
+
+type ChatMessageResponse struct {
+	Event          string          `json:"event"`
+	TaskID         string          `json:"task_id"`
+	ID             string          `json:"id"`
+	MessageID      string          `json:"message_id"`
+	ConversationID string          `json:"conversation_id"`
+	Mode           string          `json:"mode"`
+	Answer         string          `json:"answer"`
+	Metadata       MessageMetadata `json:"metadata"`
+	CreatedAt      int64           `json:"created_at"`
+}
+
+type CompletionMessageRequest struct {
+	Inputs       map[string]any `json:"inputs"`
+	ResponseMode string         `json:"response_mode"`
+	User         string         `json:"user"`
+	Files        []InputFile    `json:"files,omitempty"`
+}
+
+type CompletionMessageResponse struct {
+	Event     string          `json:"event"`
+	TaskID    string          `json:"task_id"`
+	ID        string          `json:"id"`
+	MessageID string          `json:"message_id"`
+	Mode      string          `json:"mode"`
+	Answer    string          `json:"answer"`
+	Metadata  MessageMetadata `json:"metadata"`
+	CreatedAt int64           `json:"created_at"`
+}
+
+type MessageMetadata struct {
+	Usage              Usage               `json:"usage"`
+	RetrieverResources []RetrieverResource `json:"retriever_resources"`
+}
+
+type Usage struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	PromptPrice      string  `json:"prompt_price"`
+	CompletionTokens int     `json:"completion_tokens"`
+	CompletionPrice  string  `json:"completion_price"`
+	TotalTokens      int     `json:"total_tokens"`
+	TotalPrice       string  `json:"total_price"`
+	Currency         string  `json:"currency"`
+	Latency          float64 `json:"latency"`
+}
+
+type RetrieverResource struct {
+	Position     int     `json:"position"`
+	DatasetID    string  `json:"dataset_id"`
+	DatasetName  string  `json:"dataset_name"`
+	DocumentID   string  `json:"document_id"`
+	DocumentName string  `json:"document_name"`
+	SegmentID    string  `json:"segment_id"`
+	Score        float64 `json:"score"`
+	Content      string  `json:"content"`
+}
+
+type UploadedFile struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Size      int64  `json:"size"`
+	Extension string `json:"extension"`
+	MimeType  string `json:"mime_type"`
+	CreatedBy string `json:"created_by"`
+	CreatedAt int64  `json:"created_at"`
+}
+
+type ResultResponse struct {
+	Result string `json:"result"`
+}
+
+type WorkflowRun struct {
+	ID          string         `json:"id"`
+	WorkflowID  string         `json:"workflow_id"`
+	Status      string         `json:"status"`
+	Inputs      string         `json:"inputs"` // JSON encoded inputs
+	Outputs     map[string]any `json:"outputs"`
+	Error       *string        `json:"error"`
+	TotalSteps  int            `json:"total_steps"`
+	TotalTokens int            `json:"total_tokens"`
+	ElapsedTime float64        `json:"elapsed_time"`
+	CreatedAt   int64          `json:"created_at"`
+	FinishedAt  int64          `json:"finished_at"`
+}
+
+type WorkflowLogsRequest struct {
+	Keyword string
+	Status  string // succeeded, failed or stopped
+	Page    int
+	Limit   int
+}
+
+type WorkflowLogsResponse struct {
+	Page    int           `json:"page"`
+	Limit   int           `json:"limit"`
+	Total   int           `json:"total"`
+	HasMore bool          `json:"has_more"`
+	Data    []WorkflowLog `json:"data"`
+}
+
+type WorkflowLog struct {
+	ID               string           `json:"id"`
+	WorkflowRun      WorkflowLogRun   `json:"workflow_run"`
+	CreatedFrom      string           `json:"created_from"`
+	CreatedByRole    string           `json:"created_by_role"`
+	CreatedByEndUser *WorkflowLogUser `json:"created_by_end_user"`
+	CreatedAt        int64            `json:"created_at"`
+}
+
+type WorkflowLogRun struct {
+	ID          string  `json:"id"`
+	Version     string  `json:"version"`
+	Status      string  `json:"status"`
+	Error       *string `json:"error"`
+	ElapsedTime float64 `json:"elapsed_time"`
+	TotalTokens int     `json:"total_tokens"`
+	TotalSteps  int     `json:"total_steps"`
+	CreatedAt   int64   `json:"created_at"`
+	FinishedAt  int64   `json:"finished_at"`
+}
+
+type WorkflowLogUser struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	IsAnonymous bool   `json:"is_anonymous"`
+	SessionID   string `json:"session_id"`
+}
+
+type MessageFeedbackRequest struct {
+	Rating  string // like or dislike, empty to revoke
+	User    string
+	Content string
+}
+
+type ConversationsRequest struct {
+	User   string
+	LastID string
+	Limit  int
+	SortBy string // created_at, -created_at, updated_at or -updated_at
+}
+
+type ConversationsResponse struct {
+	Limit   int            `json:"limit"`
+	HasMore bool           `json:"has_more"`
+	Data    []Conversation `json:"data"`
+}
+
+type Conversation struct {
+	ID           string         `json:"id"`
+	Name         string         `json:"name"`
+	Inputs       map[string]any `json:"inputs"`
+	Status       string         `json:"status"`
+	Introduction string         `json:"introduction"`
+	CreatedAt    int64          `json:"created_at"`
+	UpdatedAt    int64          `json:"updated_at"`
+}
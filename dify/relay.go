@@ -0,0 +1,41 @@
+package dify
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RelayStream forwards the events of a workflow or chat message stream to the client as server-sent
+// events and closes the stream. The stream should be created with c.Request.Context() so that it stops
+// when the client disconnects. An error event with the message is sent to the client before returning
+// a non-EOF error.
+func RelayStream[E any](c *gin.Context, stream *Stream[E]) error {
+	defer stream.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// disable proxy buffering, e.g. nginx
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	for {
+		_, event, data, err := stream.next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			if c.Request.Context().Err() == nil {
+				c.SSEvent(EventError, gin.H{"message": err.Error()})
+				c.Writer.Flush()
+			}
+			return err
+		}
+
+		c.SSEvent(event, string(data))
+		c.Writer.Flush()
+	}
+}
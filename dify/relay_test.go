@@ -0,0 +1,77 @@
+package dify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// relay serves a request to a gin handler that relays the stream opened by open.
+func relay[E any](t *testing.T, open func(ctx context.Context) (*Stream[E], error)) (*httptest.ResponseRecorder, error) {
+	t.Helper()
+
+	var relayErr error
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/stream", func(c *gin.Context) {
+		stream, err := open(c.Request.Context())
+		if err != nil {
+			t.Fatal(err)
+		}
+		relayErr = RelayStream(c, stream)
+	})
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/stream", nil))
+
+	return recorder, relayErr
+}
+
+func TestRelayWorkflowStream(t *testing.T) {
+	client := newWorkflowStreamClient(t, workflowEvents)
+
+	recorder, err := relay(t, func(ctx context.Context) (*WorkflowStream, error) {
+		return client.StreamWorkflow(ctx, testAPIKey, "u1", nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != "text/event-stream" || recorder.Header().Get("X-Accel-Buffering") != "no" {
+		t.Errorf("status = %d, headers = %v", recorder.Code, recorder.Header())
+	}
+
+	body := recorder.Body.String()
+	if strings.Contains(body, "ping") || strings.Contains(body, `"text":"!"`) {
+		t.Errorf("relayed pings or events after workflow_finished:\n%s", body)
+	}
+	want := "event:text_chunk\n" + `data:{"event":"text_chunk","task_id":"t1","workflow_run_id":"r1","data":{"text":"Hel"}}` + "\n\n"
+	if !strings.Contains(body, want) || strings.Count(body, "event:") != 5 {
+		t.Errorf("relayed events:\n%s", body)
+	}
+}
+
+func TestRelayChatMessageStream(t *testing.T) {
+	client := newChatStreamClient(t,
+		`data: {"event":"message","task_id":"t1","message_id":"m1","answer":"Hel"}`+"\n\n"+
+			`data: {"event":"error","task_id":"t1","message_id":"m1","status":400,"code":"completion_request_error","message":"quota exceeded"}`+"\n\n")
+
+	recorder, err := relay(t, func(ctx context.Context) (*ChatMessageStream, error) {
+		return client.StreamChatMessage(ctx, testAPIKey, &ChatMessageRequest{Query: "hi", User: "u1"})
+	})
+	if err == nil {
+		t.Fatal("want the error event returned")
+	}
+
+	body := recorder.Body.String()
+	if !strings.Contains(body, "event:message\n"+`data:{"event":"message","task_id":"t1","message_id":"m1","answer":"Hel"}`+"\n\n") {
+		t.Errorf("message not relayed:\n%s", body)
+	}
+	if !strings.Contains(body, "event:error\n") || !strings.Contains(body, "quota exceeded") {
+		t.Errorf("error not relayed:\n%s", body)
+	}
+}
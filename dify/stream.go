@@ -0,0 +1,154 @@
+package dify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/www-xu/spark/httpclient"
+	"github.com/www-xu/spark/httpclient/sse"
+	"github.com/www-xu/spark/log"
+)
+
+// Stream reads the events of a streaming response, see WorkflowStream and ChatMessageStream.
+// It isn't safe for concurrent use.
+type Stream[E any] struct {
+	ctx      context.Context
+	body     io.ReadCloser
+	reader   *sse.Reader
+	end      string // the event that ends the stream
+	parse    func(data []byte) (*E, string, error)
+	finished bool
+}
+
+// WorkflowStream reads the events of a streaming workflow run.
+type WorkflowStream = Stream[StreamEvent]
+
+// StreamWorkflow runs the workflow with response_mode streaming. The run is bound to ctx instead of
+// the client timeout, cancelling it stops reading and closes the connection. Callers must Close the stream.
+func (c *Client) StreamWorkflow(ctx context.Context, workflowId, userID string, inputs map[string]interface{}) (*WorkflowStream, error) {
+	request, err := c.newRunRequest(httpclient.WithTimeout(ctx, 0), workflowId, userID, inputs, "streaming")
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := c.openStream(request)
+	if err != nil {
+		log.WithContext(ctx).WithError(err).WithField("workflow_id", workflowId).Error("failed to stream workflow")
+		return nil, err
+	}
+
+	return newStream(ctx, body, EventWorkflowFinished, parseStreamEvent), nil
+}
+
+// openStream sends the request and returns the body of the event stream.
+func (c *Client) openStream(request *http.Request) (io.ReadCloser, error) {
+	request.Header.Set("Accept", "text/event-stream")
+
+	rawResponse, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if rawResponse.StatusCode != http.StatusOK {
+		defer rawResponse.Body.Close()
+		responseBody, _ := io.ReadAll(rawResponse.Body)
+		return nil, fmt.Errorf("[%s] | %s", rawResponse.Status, string(responseBody))
+	}
+
+	return rawResponse.Body, nil
+}
+
+func newStream[E any](ctx context.Context, body io.ReadCloser, end string, parse func(data []byte) (*E, string, error)) *Stream[E] {
+	return &Stream[E]{
+		ctx:    ctx,
+		body:   body,
+		reader: sse.NewReader(body),
+		end:    end,
+		parse:  parse,
+	}
+}
+
+// Next returns the next event, skipping pings. It returns io.EOF after the last event, workflow_finished
+// or message_end, or when the server closes the stream, a *StreamError for error events and ctx.Err()
+// after cancellation.
+func (s *Stream[E]) Next() (*E, error) {
+	event, _, _, err := s.next()
+	return event, err
+}
+
+// next also returns the name and the data of the event, which RelayStream forwards as received.
+func (s *Stream[E]) next() (*E, string, []byte, error) {
+	for {
+		if s.finished {
+			return nil, "", nil, io.EOF
+		}
+
+		data, err := s.reader.Next()
+		if err != nil {
+			if ctxErr := s.ctx.Err(); ctxErr != nil {
+				return nil, "", nil, ctxErr
+			}
+			return nil, "", nil, err
+		}
+		if data == nil {
+			continue
+		}
+
+		event, name, err := s.parse(data)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		switch name {
+		case EventPing:
+			continue
+		case s.end:
+			s.finished = true
+		case EventError:
+			s.finished = true
+			var streamError StreamError
+			err = json.Unmarshal(data, &streamError)
+			if err != nil {
+				return nil, "", nil, err
+			}
+			return nil, "", nil, &streamError
+		}
+
+		return event, name, data, nil
+	}
+}
+
+func (s *Stream[E]) Close() error {
+	return s.body.Close()
+}
+
+func parseStreamEvent(data []byte) (*StreamEvent, string, error) {
+	var envelope struct {
+		StreamEvent
+		Data json.RawMessage `json:"data"`
+	}
+	err := json.Unmarshal(data, &envelope)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid dify stream event %s: %w", string(data), err)
+	}
+
+	event := envelope.StreamEvent
+	event.Raw = data
+
+	switch event.Event {
+	case EventWorkflowStarted, EventWorkflowFinished:
+		err = json.Unmarshal(envelope.Data, &event.Workflow)
+	case EventNodeStarted, EventNodeFinished:
+		err = json.Unmarshal(envelope.Data, &event.Node)
+	case EventTextChunk:
+		err = json.Unmarshal(envelope.Data, &event.TextChunk)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid dify %s event: %w", event.Event, err)
+	}
+
+	return &event, event.Event, nil
+}
@@ -0,0 +1,151 @@
+package dify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const workflowEvents = "event: ping\n\n" +
+	`data: {"event":"workflow_started","task_id":"t1","workflow_run_id":"r1","data":{"id":"r1","workflow_id":"w1","created_at":1}}` + "\n\n" +
+	`data: {"event":"node_finished","task_id":"t1","workflow_run_id":"r1","data":{"id":"n1","node_id":"llm","node_type":"llm","status":"succeeded","outputs":{"text":"Hello"}}}` + "\n\n" +
+	`data: {"event":"text_chunk","task_id":"t1","workflow_run_id":"r1","data":{"text":"Hel"}}` + "\n\n" +
+	`data: {"event":"text_chunk","task_id":"t1","workflow_run_id":"r1","data":{"text":"lo"}}` + "\n\n" +
+	`data: {"event":"workflow_finished","task_id":"t1","workflow_run_id":"r1","data":{"id":"r1","status":"succeeded","outputs":{"answer":"Hello"},"total_tokens":12}}` + "\n\n" +
+	// nothing after workflow_finished is read
+	`data: {"event":"text_chunk","task_id":"t1","workflow_run_id":"r1","data":{"text":"!"}}` + "\n\n"
+
+func newWorkflowStreamClient(t *testing.T, events string) *Client {
+	t.Helper()
+
+	return newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		expectRequest(t, r, http.MethodPost, "/v1/workflows/run")
+		if r.Header.Get("Accept") != "text/event-stream" {
+			t.Errorf("Accept = %q, want text/event-stream", r.Header.Get("Accept"))
+		}
+		body := decodeBody(t, r)
+		if body["response_mode"] != "streaming" || body["user"] != "u1" {
+			t.Errorf("unexpected body %v", body)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, events)
+	})
+}
+
+func TestStreamWorkflow(t *testing.T) {
+	client := newWorkflowStreamClient(t, workflowEvents)
+
+	stream, err := client.StreamWorkflow(context.Background(), testAPIKey, "u1", map[string]interface{}{"query": "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	var events []string
+	var text strings.Builder
+	var finished *StreamEvent
+	for {
+		event, err := stream.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.TaskID != "t1" || event.WorkflowRunID != "r1" {
+			t.Errorf("unexpected event %+v", event)
+		}
+
+		events = append(events, event.Event)
+		switch event.Event {
+		case EventNodeFinished:
+			if event.Node == nil || event.Node.NodeID != "llm" || event.Node.Outputs["text"] != "Hello" {
+				t.Errorf("unexpected node %+v", event.Node)
+			}
+		case EventTextChunk:
+			text.WriteString(event.TextChunk.Text)
+		case EventWorkflowFinished:
+			finished = event
+		}
+	}
+
+	if strings.Join(events, ",") != "workflow_started,node_finished,text_chunk,text_chunk,workflow_finished" {
+		t.Errorf("events = %v", events)
+	}
+	if text.String() != "Hello" {
+		t.Errorf("text = %q, want Hello", text.String())
+	}
+	if finished == nil || finished.Workflow.Status != "succeeded" || finished.Workflow.Outputs["answer"] != "Hello" || finished.Workflow.TotalTokens != 12 {
+		t.Errorf("unexpected workflow_finished %+v", finished)
+	}
+}
+
+func TestStreamWorkflowErrorEvent(t *testing.T) {
+	client := newWorkflowStreamClient(t,
+		`data: {"event":"workflow_started","task_id":"t1","workflow_run_id":"r1","data":{"id":"r1"}}`+"\n\n"+
+			`data: {"event":"error","task_id":"t1","workflow_run_id":"r1","status":500,"code":"internal_server_error","message":"node failed"}`+"\n\n")
+
+	stream, err := client.StreamWorkflow(context.Background(), testAPIKey, "u1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	_, err = stream.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = stream.Next()
+	var streamError *StreamError
+	if !errors.As(err, &streamError) || streamError.Status != 500 || streamError.WorkflowRunID != "r1" || streamError.Message != "node failed" {
+		t.Fatalf("err = %v, want the error event", err)
+	}
+
+	_, err = stream.Next()
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("err = %v after the error event, want io.EOF", err)
+	}
+}
+
+func TestStreamWorkflowCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, `data: {"event":"workflow_started","task_id":"t1","data":{"id":"r1"}}`+"\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	stream, err := client.StreamWorkflow(ctx, testAPIKey, "u1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	_, err = stream.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	_, err = stream.Next()
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestStreamWorkflowStatusError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusBadRequest, `{"code":"invalid_param","message":"query is required"}`)
+	})
+
+	_, err := client.StreamWorkflow(context.Background(), testAPIKey, "u1", nil)
+	if err == nil || !strings.Contains(err.Error(), "query is required") {
+		t.Fatalf("err = %v, want the 400 body", err)
+	}
+}
@@ -0,0 +1,77 @@
+package dify
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+	"github.com/www-xu/spark/webhook"
+)
+
+const SignatureHeader = "X-Dify-Signature"
+
+// WebhookConfig configures the receiver of dify callbacks, dedupe redis needs webhook/redisdedupe to be imported.
+type WebhookConfig = webhook.Config
+
+// CallbackEvent is the payload dify workflows post back to us from an HTTP request node. The node signs
+// the raw body with HMAC-SHA256 and sends the hex digest in the X-Dify-Signature header, optionally
+// prefixed with sha256=. workflow_run_id is the sys.workflow_run_id of the run.
+type CallbackEvent struct {
+	Event         string          `json:"event"`
+	WorkflowRunID string          `json:"workflow_run_id"`
+	TaskID        string          `json:"task_id"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// EventHandler handles one callback event type, returning an error responds 500 so that dify may retry.
+type EventHandler func(ctx context.Context, event *CallbackEvent) error
+
+func newReceiver() *webhook.Receiver {
+	return webhook.NewReceiver(webhook.Options{
+		Name:            "dify",
+		SignatureHeader: SignatureHeader,
+		IDField:         "workflow_run_id",
+		KeyPrefix:       "dify:webhook:",
+		SpanFields:      []string{"task_id"},
+	})
+}
+
+// OnEvent registers the handler of a callback event type.
+func OnEvent(event string, handler EventHandler) {
+	instance.OnEvent(event, handler)
+}
+
+func (c *Component) OnEvent(event string, handler EventHandler) {
+	c.webhook.On(event, func(ctx context.Context, body []byte) error {
+		var callback CallbackEvent
+		err := webhook.Decode(body, &callback)
+		if err != nil {
+			return err
+		}
+		return handler(ctx, &callback)
+	})
+}
+
+// On registers a handler that receives the data of the event decoded into T.
+// Data that can't be decoded is rejected with 400 without calling fn.
+func On[T any](event string, fn func(ctx context.Context, event *CallbackEvent, data T) error) {
+	OnEvent(event, func(ctx context.Context, event *CallbackEvent) error {
+		var data T
+		err := webhook.Decode(event.Data, &data)
+		if err != nil {
+			return err
+		}
+		return fn(ctx, event, data)
+	})
+}
+
+// Webhook returns a gin handler that verifies the signature of dify callbacks, drops events of
+// workflow runs already handled, answers 409 to retries of a run still being handled and
+// dispatches the rest to the handlers registered with OnEvent.
+func Webhook() gin.HandlerFunc {
+	return instance.Webhook()
+}
+
+func (c *Component) Webhook() gin.HandlerFunc {
+	return c.webhook.GinHandler()
+}
@@ -0,0 +1,286 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/www-xu/spark"
+	"github.com/www-xu/spark/log"
+)
+
+const (
+	RateLimitModeRedis  = "redis"
+	RateLimitModeMemory = "memory"
+
+	// UserIDKey is the gin context key read by the "user" key extractor.
+	UserIDKey = "user_id"
+)
+
+// RateLimitRule limits requests matching Route and Method, bucketed by the Key extractor.
+type RateLimitRule struct {
+	Name   string        `mapstructure:"name"`
+	Route  string        `mapstructure:"route"`  // gin full path, e.g. /users/:id; empty matches every route
+	Method string        `mapstructure:"method"` // empty matches every method
+	Key    string        `mapstructure:"key"`    // ip (default), user, route or a registered extractor name
+	Rate   int           `mapstructure:"rate"`   // requests allowed per period
+	Period time.Duration `mapstructure:"period"`
+	Burst  int           `mapstructure:"burst"` // defaults to rate
+}
+
+type RateLimitConfig struct {
+	Mode  string          `mapstructure:"mode"` // redis (default of redismw.RateLimit) or memory
+	Rules []RateLimitRule `mapstructure:"rules"`
+}
+
+// KeyExtractor returns the bucket key of a request, an empty key skips the rule.
+type KeyExtractor func(c *gin.Context) string
+
+var (
+	keyExtractorsLock sync.RWMutex
+	keyExtractors     = map[string]KeyExtractor{
+		"ip": func(c *gin.Context) string {
+			return c.ClientIP()
+		},
+		"user": func(c *gin.Context) string {
+			if userID := c.GetString(UserIDKey); userID != "" {
+				return userID
+			}
+			return c.ClientIP()
+		},
+		"route": func(c *gin.Context) string {
+			return c.Request.Method + " " + c.FullPath()
+		},
+	}
+)
+
+// RegisterKeyExtractor makes extractor available to rules under name, rules resolve their extractor
+// when the config is loaded so it must be registered before the first request.
+func RegisterKeyExtractor(name string, extractor KeyExtractor) {
+	keyExtractorsLock.Lock()
+	defer keyExtractorsLock.Unlock()
+
+	keyExtractors[name] = extractor
+}
+
+func keyExtractor(name string) (KeyExtractor, bool) {
+	keyExtractorsLock.RLock()
+	defer keyExtractorsLock.RUnlock()
+
+	extractor, ok := keyExtractors[name]
+	return extractor, ok
+}
+
+// Limit is a GCRA limit of Rate events per Period with bursts up to Burst.
+type Limit struct {
+	Rate   int
+	Period time.Duration
+	Burst  int
+}
+
+type LimitResult struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+	ResetAfter time.Duration
+}
+
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (*LimitResult, error)
+}
+
+// RateLimit limits requests using the rules under server.rate_limit with an in process limiter,
+// use redismw.RateLimit to share the limits between replicas.
+func RateLimit() gin.HandlerFunc {
+	return RateLimitWith(func(config *RateLimitConfig) Limiter {
+		return NewMemoryLimiter()
+	})
+}
+
+// RateLimitWith limits requests using the rules under server.rate_limit and the limiter returned by newLimiter.
+// The config is loaded on the first request because middlewares are registered before spark.Init,
+// an invalid config is logged and fails every request with 500.
+func RateLimitWith(newLimiter func(config *RateLimitConfig) Limiter) gin.HandlerFunc {
+	var (
+		once    sync.Once
+		handler gin.HandlerFunc
+	)
+
+	return func(c *gin.Context) {
+		once.Do(func() {
+			config, err := spark.UnmarshalKey[RateLimitConfig]("server.rate_limit")
+			if err != nil {
+				log.WithContext(c.Request.Context()).WithError(err).Error("failed to load rate limit config")
+			}
+			if config == nil {
+				config = &RateLimitConfig{}
+			}
+
+			handler, err = RateLimitWithConfig(config, newLimiter(config))
+			if err != nil {
+				// reject requests rather than apply the rules differently than configured
+				log.WithContext(c.Request.Context()).WithError(err).Error("invalid rate limit config")
+				handler = func(c *gin.Context) {
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "invalid rate limit config"})
+				}
+			}
+		})
+
+		handler(c)
+	}
+}
+
+// RateLimitWithConfig limits requests using config and limiter. It returns an error when a rule
+// names a key extractor that isn't registered.
+func RateLimitWithConfig(config *RateLimitConfig, limiter Limiter) (gin.HandlerFunc, error) {
+	extractors := make([]KeyExtractor, len(config.Rules))
+	for i, rule := range config.Rules {
+		key := rule.Key
+		if key == "" {
+			key = "ip"
+		}
+
+		extractor, ok := keyExtractor(key)
+		if !ok {
+			return nil, fmt.Errorf("rate limit rule %s: unknown key %s", ruleName(rule, i), rule.Key)
+		}
+		extractors[i] = extractor
+	}
+
+	return func(c *gin.Context) {
+		var headerResult *LimitResult
+		var headerLimit Limit
+
+		for i, rule := range config.Rules {
+			if rule.Rate <= 0 || rule.Period <= 0 {
+				continue
+			}
+			if rule.Route != "" && rule.Route != c.FullPath() {
+				continue
+			}
+			if rule.Method != "" && rule.Method != c.Request.Method {
+				continue
+			}
+
+			key := extractors[i](c)
+			if key == "" {
+				continue
+			}
+
+			name := ruleName(rule, i)
+			limit := Limit{Rate: rule.Rate, Period: rule.Period, Burst: rule.Burst}
+
+			result, err := limiter.Allow(c.Request.Context(), fmt.Sprintf("ratelimit:%s:%s", name, key), limit)
+			if err != nil {
+				// fail open, an unavailable limiter shouldn't take the service down
+				log.WithContext(c.Request.Context()).WithError(err).WithField("rule", name).Warn("failed to check rate limit")
+				continue
+			}
+
+			if headerResult == nil || !result.Allowed || result.Remaining < headerResult.Remaining {
+				headerResult, headerLimit = result, limit
+			}
+			if !result.Allowed {
+				break
+			}
+		}
+
+		if headerResult == nil {
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(headerLimit.BurstOrRate()))
+		c.Header("RateLimit-Remaining", strconv.Itoa(headerResult.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(headerResult.ResetAfter)))
+
+		if !headerResult.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(headerResult.RetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+
+		c.Next()
+	}, nil
+}
+
+func ruleName(rule RateLimitRule, i int) string {
+	if rule.Name != "" {
+		return rule.Name
+	}
+	return strconv.Itoa(i)
+}
+
+// BurstOrRate returns Burst, or Rate when no burst is set.
+func (l Limit) BurstOrRate() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Rate
+}
+
+func (l Limit) emissionInterval() time.Duration {
+	return l.Period / time.Duration(l.Rate)
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+const memoryLimiterSweepSize = 10000
+
+// MemoryLimiter applies the same algorithm in process, for tests and single replica deployments.
+type MemoryLimiter struct {
+	lock sync.Mutex
+	tats map[string]time.Time
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		tats: make(map[string]time.Time),
+	}
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, limit Limit) (*LimitResult, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	emissionInterval := limit.emissionInterval()
+	burstOffset := emissionInterval * time.Duration(limit.BurstOrRate())
+
+	tat, ok := l.tats[key]
+	if !ok || tat.Before(now) {
+		tat = now
+	}
+
+	newTat := tat.Add(emissionInterval)
+	diff := now.Sub(newTat.Add(-burstOffset))
+	if diff < 0 {
+		return &LimitResult{
+			Allowed:    false,
+			RetryAfter: -diff,
+			ResetAfter: tat.Sub(now),
+		}, nil
+	}
+
+	if len(l.tats) >= memoryLimiterSweepSize {
+		for k, t := range l.tats {
+			if t.Before(now) {
+				delete(l.tats, k)
+			}
+		}
+	}
+	l.tats[key] = newTat
+
+	return &LimitResult{
+		Allowed:    true,
+		Remaining:  int(diff / emissionInterval),
+		ResetAfter: newTat.Sub(now),
+	}, nil
+}
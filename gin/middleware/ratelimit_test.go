@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	limiter := NewMemoryLimiter()
+	limit := Limit{Rate: 2, Period: 200 * time.Millisecond, Burst: 3}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		result, err := limiter.Allow(ctx, "k", limit)
+		if err != nil || !result.Allowed {
+			t.Fatalf("request %d within the burst: %+v, %v", i, result, err)
+		}
+		if result.Remaining != 2-i {
+			t.Errorf("request %d: remaining = %d, want %d", i, result.Remaining, 2-i)
+		}
+	}
+
+	result, _ := limiter.Allow(ctx, "k", limit)
+	if result.Allowed {
+		t.Fatal("request over the burst was allowed")
+	}
+	// one request is let through every period / rate
+	if result.RetryAfter <= 0 || result.RetryAfter > 100*time.Millisecond {
+		t.Errorf("retry after = %s, want up to the emission interval", result.RetryAfter)
+	}
+
+	if result, _ := limiter.Allow(ctx, "other", limit); !result.Allowed {
+		t.Error("keys must be limited separately")
+	}
+
+	time.Sleep(result.RetryAfter + 10*time.Millisecond)
+	if result, _ := limiter.Allow(ctx, "k", limit); !result.Allowed {
+		t.Errorf("request after retry after was rejected: %+v", result)
+	}
+}
+
+func TestMemoryLimiterSweep(t *testing.T) {
+	limiter := NewMemoryLimiter()
+	limit := Limit{Rate: 1000, Period: time.Millisecond}
+	for i := 0; i < memoryLimiterSweepSize; i++ {
+		limiter.tats[string(rune(i))] = time.Now().Add(-time.Second)
+	}
+
+	_, _ = limiter.Allow(context.Background(), "k", limit)
+	if len(limiter.tats) != 1 {
+		t.Errorf("%d keys kept, want the expired ones swept", len(limiter.tats))
+	}
+}
+
+func serveRateLimited(t *testing.T, config *RateLimitConfig, limiter Limiter) *gin.Engine {
+	t.Helper()
+
+	handler, err := RateLimitWithConfig(config, limiter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		c.Set(UserIDKey, c.GetHeader("X-User"))
+	}, handler)
+	engine.GET("/users/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	engine.POST("/users/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	return engine
+}
+
+func request(engine *gin.Engine, method, path, user string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, nil)
+	r.Header.Set("X-User", user)
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, r)
+	return recorder
+}
+
+func TestRateLimit(t *testing.T) {
+	engine := serveRateLimited(t, &RateLimitConfig{Rules: []RateLimitRule{
+		{Name: "writes", Route: "/users/:id", Method: http.MethodPost, Key: "user", Rate: 1, Period: time.Minute},
+		{Name: "reads", Key: "route", Rate: 100, Period: time.Minute},
+	}}, NewMemoryLimiter())
+
+	recorder := request(engine, http.MethodPost, "/users/1", "u1")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("first write: status = %d", recorder.Code)
+	}
+	// the rule closest to its limit sets the headers
+	if recorder.Header().Get("RateLimit-Limit") != "1" || recorder.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("headers = %v", recorder.Header())
+	}
+
+	recorder = request(engine, http.MethodPost, "/users/2", "u1")
+	if recorder.Code != http.StatusTooManyRequests || recorder.Header().Get("Retry-After") != "60" {
+		t.Errorf("second write: %d, Retry-After %q, want 429 after 60s", recorder.Code, recorder.Header().Get("Retry-After"))
+	}
+
+	if recorder = request(engine, http.MethodPost, "/users/1", "u2"); recorder.Code != http.StatusOK {
+		t.Errorf("write of another user: status = %d, want 200", recorder.Code)
+	}
+	if recorder = request(engine, http.MethodGet, "/users/1", "u1"); recorder.Code != http.StatusOK || recorder.Header().Get("RateLimit-Limit") != "100" {
+		t.Errorf("read: %d %v, want only the reads rule", recorder.Code, recorder.Header())
+	}
+}
+
+func TestRateLimitUnknownKey(t *testing.T) {
+	_, err := RateLimitWithConfig(&RateLimitConfig{Rules: []RateLimitRule{
+		{Name: "writes", Key: "unregistered", Rate: 1, Period: time.Minute},
+	}}, NewMemoryLimiter())
+	if err == nil {
+		t.Fatal("want an error for an unknown key")
+	}
+}
+
+func TestRateLimitKeyExtractor(t *testing.T) {
+	RegisterKeyExtractor("tenant", func(c *gin.Context) string {
+		return c.GetHeader("X-Tenant")
+	})
+	engine := serveRateLimited(t, &RateLimitConfig{Rules: []RateLimitRule{
+		{Name: "writes", Key: "tenant", Rate: 1, Period: time.Minute},
+	}}, NewMemoryLimiter())
+
+	for i := 0; i < 3; i++ {
+		// without a tenant the extractor returns an empty key, which skips the rule
+		if recorder := request(engine, http.MethodGet, "/users/1", ""); recorder.Code != http.StatusOK {
+			t.Errorf("request without a tenant: status = %d, want 200", recorder.Code)
+		}
+	}
+
+	tenant := func(name string) int {
+		r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		r.Header.Set("X-Tenant", name)
+		recorder := httptest.NewRecorder()
+		engine.ServeHTTP(recorder, r)
+		return recorder.Code
+	}
+	if tenant("t1") != http.StatusOK || tenant("t1") != http.StatusTooManyRequests || tenant("t2") != http.StatusOK {
+		t.Error("want requests limited per tenant")
+	}
+}
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(ctx context.Context, key string, limit Limit) (*LimitResult, error) {
+	return nil, errors.New("limiter unavailable")
+}
+
+func TestRateLimitFailsOpen(t *testing.T) {
+	engine := serveRateLimited(t, &RateLimitConfig{Rules: []RateLimitRule{
+		{Rate: 1, Period: time.Minute},
+	}}, failingLimiter{})
+
+	for i := 0; i < 2; i++ {
+		if recorder := request(engine, http.MethodGet, "/users/1", ""); recorder.Code != http.StatusOK {
+			t.Errorf("status = %d, want requests let through", recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package redismw
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/www-xu/spark"
+	"github.com/www-xu/spark/gin/middleware"
+	"github.com/www-xu/spark/log"
+	"github.com/www-xu/spark/redis"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	defaultIdempotencyTTL     = 24 * time.Hour
+	defaultIdempotencyLockTTL = time.Minute
+)
+
+// headers that describe the current request rather than the stored response
+var idempotencySkippedHeaders = map[string]bool{
+	"X-Request-Id":   true,
+	"X-Trace-Id":     true,
+	"Traceparent":    true,
+	"Tracestate":     true,
+	"Content-Length": true,
+}
+
+type IdempotencyConfig struct {
+	TTL     time.Duration `mapstructure:"ttl"`      // how long completed responses are replayed
+	LockTTL time.Duration `mapstructure:"lock_ttl"` // how long an in-flight request blocks duplicates
+}
+
+type idempotencyRecord struct {
+	Completed   bool        `json:"completed"`
+	Token       string      `json:"token,omitempty"` // identifies the request holding an in-flight key
+	Fingerprint string      `json:"fingerprint"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// completeScript replaces the in-flight record in KEYS[1] with the completed one, unless the lock expired
+// and another request took the key over.
+var completeScript = goredis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+end
+return false
+`)
+
+// releaseScript deletes the in-flight record in KEYS[1] if this request still holds it.
+var releaseScript = goredis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency replays the first response of requests carrying the same Idempotency-Key header,
+// scoped by route and user. Duplicates arriving while the first is in flight are rejected with 409,
+// and a reused key with a different body is rejected with 422. Requests without the header pass through.
+// The config under server.idempotency is loaded on the first request because middlewares are registered
+// before spark.Init.
+func Idempotency() gin.HandlerFunc {
+	var (
+		once    sync.Once
+		handler gin.HandlerFunc
+	)
+
+	return func(c *gin.Context) {
+		once.Do(func() {
+			config, err := spark.UnmarshalKey[IdempotencyConfig]("server.idempotency")
+			if err != nil {
+				log.WithContext(c.Request.Context()).WithError(err).Error("failed to load idempotency config")
+			}
+			handler = idempotency(config, redis.Get)
+		})
+
+		handler(c)
+	}
+}
+
+func idempotency(config *IdempotencyConfig, redisClient func(ctx context.Context) *goredis.Client) gin.HandlerFunc {
+	ttl, lockTTL := defaultIdempotencyTTL, defaultIdempotencyLockTTL
+	if config != nil && config.TTL > 0 {
+		ttl = config.TTL
+	}
+	if config != nil && config.LockTTL > 0 {
+		lockTTL = config.LockTTL
+	}
+
+	return func(c *gin.Context) {
+		idempotencyKey := c.GetHeader(IdempotencyKeyHeader)
+		if idempotencyKey == "" {
+			c.Next()
+			return
+		}
+
+		ctx := c.Request.Context()
+		logger := log.WithContext(ctx).WithField("idempotency_key", idempotencyKey)
+
+		fingerprint, err := requestFingerprint(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			return
+		}
+
+		key := fmt.Sprintf("idempotency:%s:%s:%s:%s", c.Request.Method, c.FullPath(), c.GetString(middleware.UserIDKey), idempotencyKey)
+		client := redisClient(ctx)
+
+		token, err := newIdempotencyToken()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to create idempotency token"})
+			return
+		}
+		inFlight, _ := json.Marshal(idempotencyRecord{Token: token, Fingerprint: fingerprint})
+		acquired, err := client.SetNX(ctx, key, inFlight, lockTTL).Result()
+		if err != nil {
+			// fail open, the handler is still expected to be safe without the cache
+			logger.WithError(err).Warn("failed to check idempotency key")
+			c.Next()
+			return
+		}
+
+		if !acquired {
+			replayIdempotentResponse(c, client, key, fingerprint)
+			return
+		}
+
+		// the client may have gone away by the time the handler returns, the key must still be released or completed
+		ctx = context.WithoutCancel(ctx)
+		release := func() {
+			err := releaseScript.Run(ctx, client, []string{key}, inFlight).Err()
+			if err != nil {
+				logger.WithError(err).Warn("failed to release idempotency key")
+			}
+		}
+
+		// Recovery runs outside this middleware, so a panicking handler must release the key here
+		defer func() {
+			if p := recover(); p != nil {
+				release()
+				panic(p)
+			}
+		}()
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		c.Next()
+
+		// server errors aren't cached so that the client may retry with the same key
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			release()
+			return
+		}
+
+		header := http.Header{}
+		for name, values := range c.Writer.Header() {
+			if !idempotencySkippedHeaders[http.CanonicalHeaderKey(name)] {
+				header[name] = values
+			}
+		}
+		completed, _ := json.Marshal(idempotencyRecord{
+			Completed:   true,
+			Fingerprint: fingerprint,
+			Status:      c.Writer.Status(),
+			Header:      header,
+			Body:        recorder.body.Bytes(),
+		})
+		err = completeScript.Run(ctx, client, []string{key}, inFlight, completed, ttl.Milliseconds()).Err()
+		if errors.Is(err, goredis.Nil) {
+			logger.Warn("idempotency key expired before the response was stored, raise server.idempotency.lock_ttl")
+		} else if err != nil {
+			logger.WithError(err).Warn("failed to store idempotent response")
+		}
+	}
+}
+
+func newIdempotencyToken() (string, error) {
+	token := make([]byte, 16)
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(token), nil
+}
+
+func replayIdempotentResponse(c *gin.Context, client *goredis.Client, key, fingerprint string) {
+	ctx := c.Request.Context()
+
+	data, err := client.Get(ctx, key).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		// the first request failed and released the key in between, ask the client to retry
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "request with the same idempotency key is in progress"})
+		return
+	}
+	if err != nil {
+		log.WithContext(ctx).WithError(err).Warn("failed to load idempotent response")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to load idempotent response"})
+		return
+	}
+
+	var record idempotencyRecord
+	if err = json.Unmarshal(data, &record); err != nil {
+		log.WithContext(ctx).WithError(err).Warn("failed to decode idempotent response")
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to load idempotent response"})
+		return
+	}
+
+	if record.Fingerprint != fingerprint {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "idempotency key was used with a different request"})
+		return
+	}
+	if !record.Completed {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "request with the same idempotency key is in progress"})
+		return
+	}
+
+	for name, values := range record.Header {
+		for _, value := range values {
+			c.Writer.Header().Add(name, value)
+		}
+	}
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Writer.WriteHeader(record.Status)
+	_, _ = c.Writer.Write(record.Body)
+	c.Abort()
+}
+
+// requestFingerprint hashes the request body, restoring it for the handler.
+func requestFingerprint(c *gin.Context) (string, error) {
+	hash := sha256.New()
+	if c.Request.Body != nil {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return "", err
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		hash.Write(body)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
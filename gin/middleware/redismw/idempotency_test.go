@@ -0,0 +1,178 @@
+package redismw
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	goredis "github.com/go-redis/redis/v8"
+)
+
+type idempotencyTest struct {
+	server *miniredis.Miniredis
+	engine *gin.Engine
+}
+
+// newIdempotencyTest serves handler on POST /orders behind the idempotency middleware, backed by miniredis.
+func newIdempotencyTest(t *testing.T, handler gin.HandlerFunc) *idempotencyTest {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(gin.CustomRecovery(func(c *gin.Context, err any) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	engine.Use(idempotency(&IdempotencyConfig{TTL: time.Hour, LockTTL: time.Minute}, func(ctx context.Context) *goredis.Client {
+		return client
+	}))
+	engine.POST("/orders", handler)
+
+	return &idempotencyTest{server: server, engine: engine}
+}
+
+func (test *idempotencyTest) post(key, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	if key != "" {
+		request.Header.Set(IdempotencyKeyHeader, key)
+	}
+	recorder := httptest.NewRecorder()
+	test.engine.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	var calls atomic.Int32
+	test := newIdempotencyTest(t, func(c *gin.Context) {
+		n := calls.Add(1)
+		c.Header("X-Order", "o1")
+		c.JSON(http.StatusCreated, gin.H{"call": n})
+	})
+
+	first := test.post("k1", `{"item":"a"}`)
+	if first.Code != http.StatusCreated || first.Body.String() != `{"call":1}` {
+		t.Fatalf("first: %d %s", first.Code, first.Body)
+	}
+
+	replay := test.post("k1", `{"item":"a"}`)
+	if replay.Code != http.StatusCreated || replay.Body.String() != `{"call":1}` {
+		t.Errorf("replay: %d %s, want the first response", replay.Code, replay.Body)
+	}
+	if replay.Header().Get(IdempotentReplayedHeader) != "true" || replay.Header().Get("X-Order") != "o1" {
+		t.Errorf("replay headers = %v", replay.Header())
+	}
+
+	if recorder := test.post("k1", `{"item":"b"}`); recorder.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key with another body: status = %d, want 422", recorder.Code)
+	}
+
+	test.post("", `{"item":"a"}`)
+	test.post("", `{"item":"a"}`)
+	if calls.Load() != 3 {
+		t.Errorf("handler called %d times, want 3", calls.Load())
+	}
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	started, finish := make(chan struct{}), make(chan struct{})
+	var calls atomic.Int32
+	test := newIdempotencyTest(t, func(c *gin.Context) {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-finish
+		}
+		c.String(http.StatusOK, "done")
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		test.post("k1", "{}")
+	}()
+	<-started
+
+	if recorder := test.post("k1", "{}"); recorder.Code != http.StatusConflict {
+		t.Errorf("duplicate in flight: status = %d, want 409", recorder.Code)
+	}
+
+	close(finish)
+	wg.Wait()
+	if recorder := test.post("k1", "{}"); recorder.Code != http.StatusOK || recorder.Body.String() != "done" {
+		t.Errorf("replay: %d %s", recorder.Code, recorder.Body)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler called %d times, want 1", calls.Load())
+	}
+}
+
+func TestIdempotencyReleasesOnFailure(t *testing.T) {
+	var calls atomic.Int32
+	test := newIdempotencyTest(t, func(c *gin.Context) {
+		switch calls.Add(1) {
+		case 1:
+			c.String(http.StatusServiceUnavailable, "unavailable")
+		case 2:
+			panic("boom")
+		default:
+			c.String(http.StatusOK, "done")
+		}
+	})
+
+	if recorder := test.post("k1", "{}"); recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("failing request: status = %d, want 503", recorder.Code)
+	}
+	if recorder := test.post("k1", "{}"); recorder.Code != http.StatusInternalServerError {
+		t.Errorf("panicking request: status = %d, want 500", recorder.Code)
+	}
+	if test.server.Exists("idempotency:POST:/orders::k1") {
+		t.Error("key is still held after the handler panicked")
+	}
+	if recorder := test.post("k1", "{}"); recorder.Code != http.StatusOK || recorder.Body.String() != "done" {
+		t.Errorf("retry: %d %s, want it handled", recorder.Code, recorder.Body)
+	}
+}
+
+func TestIdempotencyExpiredLockIsNotOverwritten(t *testing.T) {
+	started, finish := make(chan struct{}), make(chan struct{})
+	var calls atomic.Int32
+	test := newIdempotencyTest(t, func(c *gin.Context) {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-finish
+			c.String(http.StatusOK, "slow")
+			return
+		}
+		c.String(http.StatusOK, "fast")
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		test.post("k1", "{}")
+	}()
+	<-started
+
+	// the slow request outlives lock_ttl, so a retry takes the key over and completes first
+	test.server.FastForward(2 * time.Minute)
+	if recorder := test.post("k1", "{}"); recorder.Body.String() != "fast" {
+		t.Fatalf("retry after the lock expired: %d %s", recorder.Code, recorder.Body)
+	}
+
+	close(finish)
+	wg.Wait()
+	if recorder := test.post("k1", "{}"); recorder.Body.String() != "fast" {
+		t.Errorf("replay = %s, want the response stored by the request holding the key", recorder.Body)
+	}
+}
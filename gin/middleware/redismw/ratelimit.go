@@ -0,0 +1,102 @@
+package redismw
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/www-xu/spark/gin/middleware"
+	"github.com/www-xu/spark/redis"
+)
+
+// RateLimit limits requests using the rules under server.rate_limit, shared by every replica through
+// the redis component unless server.rate_limit.mode is memory.
+func RateLimit() gin.HandlerFunc {
+	return middleware.RateLimitWith(func(config *middleware.RateLimitConfig) middleware.Limiter {
+		if config.Mode == middleware.RateLimitModeMemory {
+			return middleware.NewMemoryLimiter()
+		}
+		return NewRedisLimiter()
+	})
+}
+
+// gcraScript is the generic cell rate algorithm, keeping the theoretical arrival time of the next request per key.
+const gcraScript = `
+local key = KEYS[1]
+local burst = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local period = tonumber(ARGV[3])
+
+local emission_interval = period / rate
+local burst_offset = emission_interval * burst
+
+redis.replicate_commands()
+local now = redis.call("TIME")
+now = (now[1] - 1483228800) + (now[2] / 1000000)
+
+local tat = redis.call("GET", key)
+if not tat then
+  tat = now
+else
+  tat = tonumber(tat)
+end
+tat = math.max(tat, now)
+
+local new_tat = tat + emission_interval
+local diff = now - (new_tat - burst_offset)
+if diff < 0 then
+  return {0, 0, tostring(-diff), tostring(tat - now)}
+end
+
+local reset_after = new_tat - now
+redis.call("SET", key, new_tat, "EX", math.ceil(reset_after))
+return {1, math.floor(diff / emission_interval), "0", tostring(reset_after)}
+`
+
+type RedisLimiter struct{}
+
+// NewRedisLimiter creates a limiter shared by every replica through the redis component.
+func NewRedisLimiter() *RedisLimiter {
+	return &RedisLimiter{}
+}
+
+func (l *RedisLimiter) Allow(ctx context.Context, key string, limit middleware.Limit) (*middleware.LimitResult, error) {
+	values, err := redis.Get(ctx).Eval(ctx, gcraScript, []string{key},
+		limit.BurstOrRate(), limit.Rate, limit.Period.Seconds(),
+	).Slice()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != 4 {
+		return nil, fmt.Errorf("unexpected rate limit script result: %v", values)
+	}
+
+	allowed, _ := values[0].(int64)
+	remaining, _ := values[1].(int64)
+	retryAfter, err := parseSeconds(values[2])
+	if err != nil {
+		return nil, err
+	}
+	resetAfter, err := parseSeconds(values[3])
+	if err != nil {
+		return nil, err
+	}
+
+	return &middleware.LimitResult{
+		Allowed:    allowed == 1,
+		Remaining:  int(remaining),
+		RetryAfter: retryAfter,
+		ResetAfter: resetAfter,
+	}, nil
+}
+
+func parseSeconds(value interface{}) (time.Duration, error) {
+	s, _ := value.(string)
+	seconds, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
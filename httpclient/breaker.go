@@ -0,0 +1,59 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/sony/gobreaker"
+	"github.com/www-xu/spark/log"
+)
+
+// breakerTransport keeps a circuit breaker per host. Transport errors and 5xx responses count as failures,
+// while the breaker is open calls fail fast with gobreaker.ErrOpenState.
+type breakerTransport struct {
+	config   Config
+	next     http.RoundTripper
+	breakers sync.Map // host -> *gobreaker.TwoStepCircuitBreaker
+}
+
+func newBreakerTransport(config Config, next http.RoundTripper) *breakerTransport {
+	return &breakerTransport{config: config, next: next}
+}
+
+func (t *breakerTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	done, err := t.breaker(request.URL.Host).Allow()
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := t.next.RoundTrip(request)
+	// cancellations by the caller say nothing about the health of the host
+	done(request.Context().Err() != nil || (err == nil && response.StatusCode < http.StatusInternalServerError))
+
+	return response, err
+}
+
+func (t *breakerTransport) breaker(host string) *gobreaker.TwoStepCircuitBreaker {
+	if breaker, ok := t.breakers.Load(host); ok {
+		return breaker.(*gobreaker.TwoStepCircuitBreaker)
+	}
+
+	breaker, _ := t.breakers.LoadOrStore(host, gobreaker.NewTwoStepCircuitBreaker(gobreaker.Settings{
+		Name:        t.config.Name + " " + host,
+		MaxRequests: t.config.Breaker.HalfOpenRequests,
+		Timeout:     t.config.Breaker.OpenTimeout,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= t.config.Breaker.ConsecutiveFailures
+		},
+		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
+			log.WithContext(context.Background()).WithFields(map[string]interface{}{
+				"breaker": name,
+				"from":    from.String(),
+				"to":      to.String(),
+			}).Warn("http circuit breaker state changed")
+		},
+	}))
+
+	return breaker.(*gobreaker.TwoStepCircuitBreaker)
+}
@@ -0,0 +1,64 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sony/gobreaker"
+)
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	server, calls := newTestServer(t, func(w http.ResponseWriter, r *http.Request, n int32) {
+		if n <= 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+
+	client := New(Config{Breaker: BreakerConfig{Enabled: true, ConsecutiveFailures: 2, OpenTimeout: 50 * time.Millisecond}})
+
+	for i := 0; i < 2; i++ {
+		response, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = response.Body.Close()
+	}
+
+	_, err := client.Get(server.URL)
+	if !errors.Is(err, gobreaker.ErrOpenState) {
+		t.Fatalf("err = %v, want the open breaker", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("%d calls reached the server, want 2", calls.Load())
+	}
+
+	// after OpenTimeout a probe is let through and closes the breaker again
+	time.Sleep(60 * time.Millisecond)
+	response, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("probe status = %d, want 200", response.StatusCode)
+	}
+}
+
+func TestBreakerOpenStateIsNotRetried(t *testing.T) {
+	server, calls := newTestServer(t, func(w http.ResponseWriter, r *http.Request, n int32) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	config := retryConfig()
+	config.Breaker = BreakerConfig{Enabled: true, ConsecutiveFailures: 2, OpenTimeout: time.Minute}
+
+	_, err := New(config).Get(server.URL)
+	if !errors.Is(err, gobreaker.ErrOpenState) {
+		t.Fatalf("err = %v, want the open breaker", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("%d calls reached the server, want 2", calls.Load())
+	}
+}
@@ -0,0 +1,124 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+const (
+	defaultMaxIdleConnsPerHost = 16
+	defaultIdleConnTimeout     = 90 * time.Second
+	defaultRetryWaitMin        = 200 * time.Millisecond
+	defaultRetryWaitMax        = 10 * time.Second
+	defaultBreakerFailures     = 5
+	defaultBreakerOpenTimeout  = 30 * time.Second
+	defaultMaxLogBodySize      = 4 << 10
+)
+
+// New returns an http.Client whose transport, from the outside in, applies the call timeout,
+// retries, the per-host circuit breaker, logging and otelhttp tracing. Logging and tracing
+// sit inside the retries so that every attempt gets its own log entry and span.
+func New(config Config) *http.Client {
+	config = config.withDefaults()
+
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	base.IdleConnTimeout = config.IdleConnTimeout
+
+	var transport http.RoundTripper = otelhttp.NewTransport(base,
+		otelhttp.WithSpanNameFormatter(func(operation string, r *http.Request) string {
+			return config.Name + " " + r.Method
+		}),
+	)
+	if config.Log.Enabled {
+		transport = newLogTransport(config, transport)
+	}
+	if config.Breaker.Enabled {
+		transport = newBreakerTransport(config, transport)
+	}
+	if config.Retry.MaxRetries > 0 {
+		transport = &retryTransport{config: config.Retry, next: transport}
+	}
+	transport = &timeoutTransport{timeout: config.Timeout, next: transport}
+
+	return &http.Client{Transport: transport}
+}
+
+func (config Config) withDefaults() Config {
+	if config.Name == "" {
+		config.Name = "http"
+	}
+	if config.MaxIdleConnsPerHost <= 0 {
+		config.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	}
+	if config.IdleConnTimeout <= 0 {
+		config.IdleConnTimeout = defaultIdleConnTimeout
+	}
+	if config.Retry.WaitMin <= 0 {
+		config.Retry.WaitMin = defaultRetryWaitMin
+	}
+	if config.Retry.WaitMax <= 0 {
+		config.Retry.WaitMax = defaultRetryWaitMax
+	}
+	if config.Breaker.ConsecutiveFailures == 0 {
+		config.Breaker.ConsecutiveFailures = defaultBreakerFailures
+	}
+	if config.Breaker.OpenTimeout <= 0 {
+		config.Breaker.OpenTimeout = defaultBreakerOpenTimeout
+	}
+	if config.Log.MaxBodySize <= 0 {
+		config.Log.MaxBodySize = defaultMaxLogBodySize
+	}
+	return config
+}
+
+type timeoutKey struct{}
+
+// WithTimeout overrides the configured timeout for calls made with the returned context,
+// 0 disables it, e.g. for long-lived streaming responses.
+func WithTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, timeoutKey{}, timeout)
+}
+
+// timeoutTransport bounds the whole call, including retries and reading the response body.
+type timeoutTransport struct {
+	timeout time.Duration
+	next    http.RoundTripper
+}
+
+func (t *timeoutTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	timeout := t.timeout
+	if value, ok := request.Context().Value(timeoutKey{}).(time.Duration); ok {
+		timeout = value
+	}
+	if timeout <= 0 {
+		return t.next.RoundTrip(request)
+	}
+
+	ctx, cancel := context.WithTimeout(request.Context(), timeout)
+	response, err := t.next.RoundTrip(request.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// the deadline must outlive RoundTrip until the caller has read the body
+	response.Body = &cancelBody{ReadCloser: response.Body, cancel: cancel}
+
+	return response, nil
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
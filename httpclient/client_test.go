@@ -0,0 +1,59 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTimeoutBoundsTheCall(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request, n int32) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+
+	_, err := New(Config{Timeout: 50 * time.Millisecond}).Get(server.URL)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the deadline", err)
+	}
+}
+
+func TestTimeoutCoversReadingTheBody(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request, n int32) {
+		_, _ = io.WriteString(w, "head")
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	})
+
+	response, err := New(Config{Timeout: 50 * time.Millisecond}).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	_, err = io.ReadAll(response.Body)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the deadline while reading the body", err)
+	}
+}
+
+func TestWithTimeoutOverridesTheConfig(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request, n int32) {
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	request, _ := http.NewRequestWithContext(WithTimeout(context.Background(), 0), http.MethodGet, server.URL, nil)
+	response, err := New(Config{Timeout: 20 * time.Millisecond}).Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = response.Body.Close()
+}
@@ -0,0 +1,38 @@
+package httpclient
+
+import "time"
+
+type RetryConfig struct {
+	MaxRetries int           `mapstructure:"max_retries"` // 0 disables retries
+	WaitMin    time.Duration `mapstructure:"wait_min"`    // first backoff, doubled on every retry
+	WaitMax    time.Duration `mapstructure:"wait_max"`    // upper bound of the backoff and of Retry-After
+}
+
+type BreakerConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// ConsecutiveFailures opens the breaker of a host after that many failed calls in a row.
+	ConsecutiveFailures uint32 `mapstructure:"consecutive_failures"`
+	// OpenTimeout is how long the breaker stays open before letting a probe request through.
+	OpenTimeout time.Duration `mapstructure:"open_timeout"`
+	// HalfOpenRequests is the number of probe requests allowed while half open.
+	HalfOpenRequests uint32 `mapstructure:"half_open_requests"`
+}
+
+type LogConfig struct {
+	Enabled       bool     `mapstructure:"enabled"`
+	Body          bool     `mapstructure:"body"`           // log request and response bodies, except event streams
+	MaxBodySize   int      `mapstructure:"max_body_size"`  // bodies are truncated to this many bytes
+	RedactHeaders []string `mapstructure:"redact_headers"` // added to the default sensitive headers
+	RedactFields  []string `mapstructure:"redact_fields"`  // query parameters and JSON fields, added to the defaults
+}
+
+type Config struct {
+	Name    string        `mapstructure:"name"`    // used in logs and span names
+	Timeout time.Duration `mapstructure:"timeout"` // timeout of a call including retries, 0 means none
+	Retry   RetryConfig   `mapstructure:"retry"`
+	Breaker BreakerConfig `mapstructure:"breaker"`
+	Log     LogConfig     `mapstructure:"log"`
+
+	MaxIdleConnsPerHost int           `mapstructure:"max_idle_conns_per_host"`
+	IdleConnTimeout     time.Duration `mapstructure:"idle_conn_timeout"`
+}
@@ -0,0 +1,27 @@
+module github.com/www-xu/spark/httpclient
+
+go 1.24.2
+
+require (
+	github.com/sirupsen/logrus v1.9.3
+	github.com/sony/gobreaker v1.0.0
+	github.com/www-xu/spark/log v0.0.0-20250705142410-605db6152998
+	go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp v0.62.0
+)
+
+require (
+	github.com/felixge/httpsnoop v1.0.4 // indirect
+	github.com/go-logr/logr v1.4.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	go.opentelemetry.io/auto/sdk v1.1.0 // indirect
+	go.opentelemetry.io/otel v1.37.0 // indirect
+	go.opentelemetry.io/otel/metric v1.37.0 // indirect
+	go.opentelemetry.io/otel/trace v1.37.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+	golang.org/x/text v0.26.0 // indirect
+	gorm.io/gorm v1.30.0 // indirect
+)
+
+replace github.com/www-xu/spark/log => ../log
@@ -0,0 +1,143 @@
+package httpclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/www-xu/spark/log"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	defaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-N8n-Api-Key"}
+	defaultRedactFields  = []string{"api_key", "apikey", "access_token", "refresh_token", "token", "password", "secret", "signature"}
+)
+
+// logTransport logs every attempt with sensitive headers, query parameters and JSON fields redacted.
+type logTransport struct {
+	config        Config
+	next          http.RoundTripper
+	redactHeaders map[string]bool
+	redactFields  map[string]bool
+	fieldPattern  *regexp.Regexp
+}
+
+func newLogTransport(config Config, next http.RoundTripper) *logTransport {
+	t := &logTransport{
+		config:        config,
+		next:          next,
+		redactHeaders: map[string]bool{},
+		redactFields:  map[string]bool{},
+	}
+
+	for _, header := range append(defaultRedactHeaders, config.Log.RedactHeaders...) {
+		t.redactHeaders[http.CanonicalHeaderKey(header)] = true
+	}
+
+	var fields []string
+	for _, field := range append(defaultRedactFields, config.Log.RedactFields...) {
+		t.redactFields[strings.ToLower(field)] = true
+		fields = append(fields, regexp.QuoteMeta(field))
+	}
+	// matches "field": "value" in JSON bodies, values that aren't strings are left as they are
+	t.fieldPattern = regexp.MustCompile(`(?i)("(?:` + strings.Join(fields, "|") + `)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+	return t
+}
+
+func (t *logTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	fields := logrus.Fields{
+		"client":          t.config.Name,
+		"method":          request.Method,
+		"url":             t.redactURL(request.URL),
+		"request_headers": t.redactHeader(request.Header),
+	}
+	if t.config.Log.Body && request.GetBody != nil {
+		body, err := request.GetBody()
+		if err == nil {
+			fields["request_body"] = t.readBody(body)
+			_ = body.Close()
+		}
+	}
+
+	start := time.Now()
+	response, err := t.next.RoundTrip(request)
+	fields["duration_ms"] = time.Since(start).Milliseconds()
+
+	entry := log.WithContext(request.Context())
+	if err != nil {
+		entry.WithFields(fields).WithError(err).Warn("http request failed")
+		return nil, err
+	}
+
+	fields["status"] = response.StatusCode
+	fields["response_headers"] = t.redactHeader(response.Header)
+	if t.config.Log.Body && !strings.HasPrefix(response.Header.Get("Content-Type"), "text/event-stream") {
+		response.Body = t.teeBody(response.Body, fields)
+	}
+
+	if response.StatusCode >= http.StatusBadRequest {
+		entry.WithFields(fields).Warn("http request finished")
+	} else {
+		entry.WithFields(fields).Info("http request finished")
+	}
+
+	return response, nil
+}
+
+// teeBody reads up to MaxBodySize bytes for the log and puts them back in front of the rest of the body.
+func (t *logTransport) teeBody(body io.ReadCloser, fields logrus.Fields) io.ReadCloser {
+	head, _ := io.ReadAll(io.LimitReader(body, int64(t.config.Log.MaxBodySize)))
+	fields["response_body"] = t.redactBody(head)
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: io.MultiReader(bytes.NewReader(head), body),
+		Closer: body,
+	}
+}
+
+func (t *logTransport) readBody(body io.Reader) string {
+	head, _ := io.ReadAll(io.LimitReader(body, int64(t.config.Log.MaxBodySize)))
+	return t.redactBody(head)
+}
+
+func (t *logTransport) redactBody(body []byte) string {
+	return t.fieldPattern.ReplaceAllString(string(body), `$1"`+redacted+`"`)
+}
+
+func (t *logTransport) redactHeader(header http.Header) map[string]string {
+	redactedHeader := make(map[string]string, len(header))
+	for key, values := range header {
+		if t.redactHeaders[http.CanonicalHeaderKey(key)] {
+			redactedHeader[key] = redacted
+			continue
+		}
+		redactedHeader[key] = strings.Join(values, ", ")
+	}
+	return redactedHeader
+}
+
+func (t *logTransport) redactURL(u *url.URL) string {
+	redactedURL := *u
+	redactedURL.User = nil
+
+	query := redactedURL.Query()
+	for key := range query {
+		if t.redactFields[strings.ToLower(key)] {
+			query.Set(key, redacted)
+		}
+	}
+	redactedURL.RawQuery = query.Encode()
+
+	return redactedURL.String()
+}
@@ -0,0 +1,61 @@
+package httpclient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestLogRedactsSecrets(t *testing.T) {
+	hook := test.NewGlobal()
+	defer hook.Reset()
+
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request, n int32) {
+		w.Header().Set("Set-Cookie", "session=s1")
+		_, _ = io.WriteString(w, `{"access_token":"at-1","name":"alice"}`)
+	})
+
+	client := New(Config{Log: LogConfig{Enabled: true, Body: true, RedactHeaders: []string{"X-Custom-Secret"}, RedactFields: []string{"pin"}}})
+
+	request, _ := http.NewRequest(http.MethodPost, server.URL+"/path?api_key=k1&page=2", strings.NewReader(`{"password":"p\"1","pin":"1234","count":3}`))
+	request.Header.Set("Authorization", "Bearer t1")
+	request.Header.Set("X-Custom-Secret", "c1")
+	response, err := client.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+
+	if string(body) != `{"access_token":"at-1","name":"alice"}` {
+		t.Errorf("logging changed the response body to %s", body)
+	}
+
+	entry := hook.LastEntry()
+	if entry == nil || entry.Level != logrus.InfoLevel {
+		t.Fatalf("unexpected log entry %+v", entry)
+	}
+
+	for _, secret := range []string{"k1", "t1", "c1", "p\\\"1", "1234", "s1", "at-1"} {
+		for key, value := range entry.Data {
+			if strings.Contains(fmt.Sprint(value), secret) {
+				t.Errorf("%s leaks %q: %v", key, secret, value)
+			}
+		}
+	}
+
+	if url := entry.Data["url"].(string); !strings.Contains(url, "page=2") || !strings.Contains(url, "api_key=%5BREDACTED%5D") {
+		t.Errorf("url = %s", url)
+	}
+	if requestBody := entry.Data["request_body"].(string); !strings.Contains(requestBody, `"count":3`) {
+		t.Errorf("request_body = %s", requestBody)
+	}
+	if responseBody := entry.Data["response_body"].(string); !strings.Contains(responseBody, `"name":"alice"`) {
+		t.Errorf("response_body = %s", responseBody)
+	}
+}
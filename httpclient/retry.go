@@ -0,0 +1,121 @@
+package httpclient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sony/gobreaker"
+)
+
+// retryTransport retries 429 and 5xx responses with exponential backoff, honouring Retry-After up to WaitMax.
+// Requests that aren't idempotent are only retried on 429 and 503, which mean the server didn't
+// process them, unless they carry an Idempotency-Key header.
+type retryTransport struct {
+	config RetryConfig
+	next   http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	ctx := request.Context()
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && request.Body != nil {
+			// the previous attempt consumed the body, requests built from bytes or strings can replay it
+			body, err := request.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			request = request.Clone(ctx)
+			request.Body = body
+		}
+
+		response, err := t.next.RoundTrip(request)
+		if attempt >= t.config.MaxRetries || !t.shouldRetry(request, response, err) {
+			return response, err
+		}
+
+		wait := t.backoff(attempt)
+		if response != nil {
+			if retryAfter, ok := parseRetryAfter(response.Header.Get("Retry-After")); ok {
+				// a server asking for hours mustn't park the caller that long
+				wait = min(retryAfter, t.config.WaitMax)
+			}
+			_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 4<<10))
+			_ = response.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (t *retryTransport) shouldRetry(request *http.Request, response *http.Response, err error) bool {
+	if request.Body != nil && request.GetBody == nil {
+		return false
+	}
+	if request.Context().Err() != nil {
+		return false
+	}
+	if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+		return false
+	}
+
+	idempotent := isIdempotent(request)
+	if err != nil {
+		return idempotent
+	}
+
+	switch {
+	case response.StatusCode == http.StatusTooManyRequests, response.StatusCode == http.StatusServiceUnavailable:
+		return true
+	case response.StatusCode >= http.StatusInternalServerError:
+		return idempotent
+	default:
+		return false
+	}
+}
+
+func isIdempotent(request *http.Request) bool {
+	switch request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return request.Header.Get("Idempotency-Key") != ""
+}
+
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	wait := t.config.WaitMin << attempt
+	if wait <= 0 || wait > t.config.WaitMax {
+		return t.config.WaitMax
+	}
+	return wait
+}
+
+// parseRetryAfter accepts both delay seconds and an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+
+	return 0, false
+}
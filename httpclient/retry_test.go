@@ -0,0 +1,164 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestServer returns a server answering the n-th call, counted from 1, with handler.
+func newTestServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request, n int32)) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler(w, r, calls.Add(1))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &calls
+}
+
+func retryConfig() Config {
+	return Config{Retry: RetryConfig{MaxRetries: 3, WaitMin: time.Millisecond, WaitMax: 20 * time.Millisecond}}
+}
+
+func TestRetryReplaysPostBody(t *testing.T) {
+	server, calls := newTestServer(t, func(w http.ResponseWriter, r *http.Request, n int32) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"q":1}` {
+			t.Errorf("attempt %d body = %q", n, body)
+		}
+		if n < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	})
+
+	response, err := New(retryConfig()).Post(server.URL, "application/json", strings.NewReader(`{"q":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK || calls.Load() != 3 {
+		t.Errorf("status = %d after %d calls, want 200 after 3", response.StatusCode, calls.Load())
+	}
+}
+
+func TestRetrySkipsNonIdempotentServerErrors(t *testing.T) {
+	server, calls := newTestServer(t, func(w http.ResponseWriter, r *http.Request, n int32) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	response, err := New(retryConfig()).Post(server.URL, "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusInternalServerError || calls.Load() != 1 {
+		t.Errorf("status = %d after %d calls, want 500 after 1", response.StatusCode, calls.Load())
+	}
+
+	request, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{}`))
+	request.Header.Set("Idempotency-Key", "k1")
+	response, err = New(retryConfig()).Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	if calls.Load() != 5 {
+		t.Errorf("%d calls with an Idempotency-Key, want 1 + 4", calls.Load())
+	}
+}
+
+func TestRetryGivesUpAfterMaxRetries(t *testing.T) {
+	server, calls := newTestServer(t, func(w http.ResponseWriter, r *http.Request, n int32) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	response, err := New(retryConfig()).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusBadGateway || calls.Load() != 4 {
+		t.Errorf("status = %d after %d calls, want 502 after 4", response.StatusCode, calls.Load())
+	}
+}
+
+func TestRetryAfterIsClampedToWaitMax(t *testing.T) {
+	server, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request, n int32) {
+		if n == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	})
+
+	start := time.Now()
+	response, err := New(retryConfig()).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", response.StatusCode)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("waited %s for Retry-After, want at most WaitMax", elapsed)
+	}
+}
+
+func TestRetryStopsWhenContextIsCancelled(t *testing.T) {
+	server, calls := newTestServer(t, func(w http.ResponseWriter, r *http.Request, n int32) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	config := retryConfig()
+	config.Retry.WaitMin = time.Second
+	config.Retry.WaitMax = time.Second
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	request, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+
+	_, err := New(config).Do(request)
+	if err == nil || calls.Load() != 1 {
+		t.Errorf("err = %v after %d calls, want the context error after 1", err, calls.Load())
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+	}
+	for _, test := range tests {
+		got, ok := parseRetryAfter(test.value)
+		if got != test.want || ok != test.ok {
+			t.Errorf("parseRetryAfter(%q) = %s, %v, want %s, %v", test.value, got, ok, test.want, test.ok)
+		}
+	}
+
+	got, ok := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	if !ok || got <= 58*time.Second || got > time.Minute {
+		t.Errorf("parseRetryAfter(date in a minute) = %s, %v", got, ok)
+	}
+}
@@ -0,0 +1,50 @@
+// Package sse reads server-sent event streams, such as the streaming responses of LLM and workflow APIs.
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// Reader reads the data of the events of a stream. It isn't safe for concurrent use.
+type Reader struct {
+	reader *bufio.Reader
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{reader: bufio.NewReader(r)}
+}
+
+// Next returns the data of the next event, joining multi-line data with newlines, or nil for events
+// without data such as comments and pings. It returns io.EOF when the stream ends.
+func (r *Reader) Next() ([]byte, error) {
+	var data []byte
+	for {
+		// the last line may end without a newline when the server closes the stream
+		line, err := r.reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) == 0 && err == nil {
+			return data, nil
+		}
+
+		if value, ok := bytes.CutPrefix(line, []byte("data:")); ok {
+			if len(data) > 0 {
+				data = append(data, '\n')
+			}
+			data = append(data, bytes.TrimPrefix(value, []byte(" "))...)
+		}
+
+		if err != nil {
+			if len(data) > 0 {
+				return data, nil
+			}
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package sse
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	reader := NewReader(strings.NewReader(": keep-alive\n\n" +
+		"event: message\r\ndata: {\"a\":1}\r\n\r\n" +
+		"data:first\ndata: second\nid: 2\n\n" +
+		"data: [DONE]"))
+
+	for _, want := range []string{"", `{"a":1}`, "first\nsecond", "[DONE]"} {
+		data, err := reader.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("data = %q, want %q", data, want)
+		}
+		if want == "" && data != nil {
+			t.Error("want nil data for a comment")
+		}
+	}
+
+	_, err := reader.Next()
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("err = %v, want io.EOF", err)
+	}
+}
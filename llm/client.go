@@ -0,0 +1,175 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/www-xu/spark/httpclient"
+	"github.com/www-xu/spark/log"
+)
+
+const (
+	defaultTimeout    = 2 * time.Minute
+	defaultSystem     = "openai"
+	defaultMaxRetries = 3
+)
+
+var ErrNoChoices = errors.New("llm response has no choices")
+
+type Client struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	system     string
+	timeout    time.Duration
+	httpClient *http.Client
+	telemetry  *telemetry
+}
+
+func NewClient(baseURL, apiKey string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		system:  defaultSystem,
+		timeout: defaultTimeout,
+		httpClient: httpclient.New(httpclient.Config{
+			Name:  "llm",
+			Retry: httpclient.RetryConfig{MaxRetries: defaultMaxRetries},
+		}),
+		telemetry: newTelemetry(),
+	}
+}
+
+// WithModel returns a copy of the client that uses model for requests without one.
+func (c *Client) WithModel(model string) *Client {
+	client := *c
+	client.model = model
+	return &client
+}
+
+// CreateChatCompletion sends a blocking chat completion request. Rate limited calls are retried by the
+// transport, honouring Retry-After.
+func (c *Client) CreateChatCompletion(ctx context.Context, request ChatCompletionRequest) (response *ChatCompletionResponse, err error) {
+	request.Stream = false
+	request.StreamOptions = nil
+	if request.Model == "" {
+		request.Model = c.model
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	ctx, op := c.telemetry.start(ctx, c.system, &request)
+	defer func() {
+		if err != nil {
+			op.end("", "", nil, nil, err)
+			log.WithContext(ctx).WithError(err).WithField("model", request.Model).Error("failed to create chat completion")
+			return
+		}
+		op.end(response.ID, response.Model, finishReasons(response.Choices), response.Usage, nil)
+	}()
+
+	responseBody, err := c.post(ctx, request, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer responseBody.Close()
+
+	err = json.NewDecoder(responseBody).Decode(&response)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Choices) == 0 {
+		return nil, ErrNoChoices
+	}
+
+	return response, nil
+}
+
+// Complete sends the messages and returns the content of the first choice.
+func (c *Client) Complete(ctx context.Context, messages ...Message) (string, error) {
+	response, err := c.CreateChatCompletion(ctx, ChatCompletionRequest{Messages: messages})
+	if err != nil {
+		return "", err
+	}
+
+	return response.Choices[0].Message.Content, nil
+}
+
+// CreateAs sends the request and decodes the reply of the first choice into T. Set request.ResponseFormat,
+// usually with JSONSchemaFormat, so that the model answers with JSON matching T.
+func CreateAs[T any](ctx context.Context, client *Client, request ChatCompletionRequest) (*T, *ChatCompletionResponse, error) {
+	response, err := client.CreateChatCompletion(ctx, request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var value T
+	err = json.Unmarshal([]byte(response.Choices[0].Message.Content), &value)
+	if err != nil {
+		return nil, response, fmt.Errorf("decode llm structured output: %w", err)
+	}
+
+	return &value, response, nil
+}
+
+// post sends the request to /chat/completions and returns the body of a successful response.
+func (c *Client) post(ctx context.Context, request ChatCompletionRequest, accept string) (io.ReadCloser, error) {
+	requestBytes, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(requestBytes))
+	if err != nil {
+		return nil, err
+	}
+	httpRequest.Header.Set("Accept", accept)
+	httpRequest.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		httpRequest.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
+	}
+
+	rawResponse, err := c.httpClient.Do(httpRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	if rawResponse.StatusCode < 200 || rawResponse.StatusCode > 299 {
+		defer rawResponse.Body.Close()
+		responseBody, _ := io.ReadAll(rawResponse.Body)
+		return nil, newAPIError(rawResponse, responseBody)
+	}
+
+	return rawResponse.Body, nil
+}
+
+func newAPIError(rawResponse *http.Response, responseBody []byte) error {
+	var response errorResponse
+	if json.Unmarshal(responseBody, &response) == nil && response.Error != nil {
+		response.Error.StatusCode = rawResponse.StatusCode
+		return response.Error
+	}
+
+	return &APIError{
+		StatusCode: rawResponse.StatusCode,
+		Message:    fmt.Sprintf("[%s] | %s", rawResponse.Status, string(responseBody)),
+	}
+}
+
+func finishReasons(choices []Choice) []string {
+	reasons := make([]string, 0, len(choices))
+	for _, choice := range choices {
+		if choice.FinishReason != "" {
+			reasons = append(reasons, choice.FinishReason)
+		}
+	}
+	return reasons
+}
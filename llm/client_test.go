@@ -0,0 +1,163 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+const testAPIKey = "sk-test"
+
+// newTestClient returns a client of a server whose /chat/completions is handled by handler, which
+// also checks the api key and decodes the request.
+func newTestClient(t *testing.T, handler func(w http.ResponseWriter, r *http.Request, request ChatCompletionRequest)) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer "+testAPIKey {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+
+		var request ChatCompletionRequest
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		handler(w, r, request)
+	}))
+	t.Cleanup(server.Close)
+
+	return NewClient(server.URL+"/v1/", testAPIKey).WithModel("gpt-test")
+}
+
+func writeJSON(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, body)
+}
+
+func TestCreateChatCompletion(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request, request ChatCompletionRequest) {
+		if request.Model != "gpt-test" || request.Stream || request.StreamOptions != nil {
+			t.Errorf("unexpected request %+v", request)
+		}
+		if len(request.Messages) != 2 || request.Messages[1].Role != RoleUser || request.Messages[1].Content != "hi" {
+			t.Errorf("messages = %+v", request.Messages)
+		}
+		writeJSON(w, http.StatusOK, `{"id":"c1","model":"gpt-test-0613","choices":[{"index":0,"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":1,"total_tokens":6}}`)
+	})
+
+	content, err := client.Complete(context.Background(), SystemMessage("be brief"), UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "hello" {
+		t.Errorf("content = %q", content)
+	}
+}
+
+func TestCreateChatCompletionNoChoices(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request, request ChatCompletionRequest) {
+		writeJSON(w, http.StatusOK, `{"id":"c1","choices":[]}`)
+	})
+
+	_, err := client.Complete(context.Background(), UserMessage("hi"))
+	if !errors.Is(err, ErrNoChoices) {
+		t.Errorf("err = %v, want ErrNoChoices", err)
+	}
+}
+
+func TestCreateAs(t *testing.T) {
+	type weather struct {
+		City        string  `json:"city"`
+		Temperature float64 `json:"temperature"`
+	}
+
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request, request ChatCompletionRequest) {
+		format := request.ResponseFormat
+		if format == nil || format.Type != "json_schema" || format.JSONSchema.Name != "weather" || !format.JSONSchema.Strict {
+			t.Errorf("response format = %+v", format)
+		}
+		content := `{"city":"Paris","temperature":21.5}`
+		if request.Messages[0].Content == "invalid" {
+			content = "it is sunny"
+		}
+		response, _ := json.Marshal(ChatCompletionResponse{
+			ID:      "c1",
+			Choices: []Choice{{Message: AssistantMessage(content), FinishReason: FinishReasonStop}},
+		})
+		writeJSON(w, http.StatusOK, string(response))
+	})
+
+	request := func(content string) ChatCompletionRequest {
+		return ChatCompletionRequest{
+			Messages:       []Message{UserMessage(content)},
+			ResponseFormat: JSONSchemaFormat("weather", map[string]any{"type": "object"}),
+		}
+	}
+
+	value, response, err := CreateAs[weather](context.Background(), client, request("weather in Paris"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value.City != "Paris" || value.Temperature != 21.5 || response.ID != "c1" {
+		t.Errorf("unexpected value %+v", value)
+	}
+
+	_, response, err = CreateAs[weather](context.Background(), client, request("invalid"))
+	if err == nil || response == nil {
+		t.Errorf("err = %v, want a decode error along with the response", err)
+	}
+}
+
+func TestCreateChatCompletionRetriesRateLimits(t *testing.T) {
+	var calls atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request, request ChatCompletionRequest) {
+		if calls.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			writeJSON(w, http.StatusTooManyRequests, `{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`)
+			return
+		}
+		writeJSON(w, http.StatusOK, `{"id":"c1","choices":[{"message":{"role":"assistant","content":"hello"}}]}`)
+	})
+
+	content, err := client.Complete(context.Background(), UserMessage("hi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "hello" || calls.Load() != 3 {
+		t.Errorf("content = %q after %d calls, want the third answer", content, calls.Load())
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request, request ChatCompletionRequest) {
+		if request.Model == "plain" {
+			writeJSON(w, http.StatusBadGateway, `upstream down`)
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, `{"error":{"message":"unknown model","type":"invalid_request_error","param":"model","code":"model_not_found"}}`)
+	})
+
+	_, err := client.CreateChatCompletion(context.Background(), ChatCompletionRequest{Messages: []Message{UserMessage("hi")}})
+	var apiError *APIError
+	if !errors.As(err, &apiError) {
+		t.Fatalf("err = %v, want an *APIError", err)
+	}
+	if apiError.StatusCode != http.StatusBadRequest || apiError.Code != "model_not_found" || apiError.Param != "model" {
+		t.Errorf("unexpected error %+v", apiError)
+	}
+
+	_, err = client.CreateChatCompletion(context.Background(), ChatCompletionRequest{Model: "plain"})
+	if !errors.As(err, &apiError) || apiError.StatusCode != http.StatusBadGateway {
+		t.Errorf("err = %v, want an *APIError with the status", err)
+	}
+}
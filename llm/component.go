@@ -0,0 +1,106 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/www-xu/spark"
+	"github.com/www-xu/spark/httpclient"
+)
+
+type Component struct {
+	ctx      *spark.ApplicationContext
+	config   *Config
+	instance *Client
+}
+
+func NewComponent() *Component {
+	return &Component{}
+}
+
+var instance *Component
+
+func init() {
+	instance = NewComponent()
+
+	spark.RegisterApplicationInitEventListener(instance)
+	spark.RegisterApplicationStopEventListener(instance)
+}
+
+func (c *Component) Instantiate() error {
+	err := c.ctx.UnmarshalKey("llm", &c.config)
+	if err != nil {
+		return err
+	}
+
+	if c.config == nil {
+		return errors.New("llm config isn't found")
+	}
+
+	if c.config.BaseURL == "" {
+		return errors.New("llm.base_url is required")
+	}
+
+	apiKey := c.config.APIKey
+	if c.config.APIKeyEnv != "" {
+		var ok bool
+		apiKey, ok = os.LookupEnv(c.config.APIKeyEnv)
+		if !ok || apiKey == "" {
+			return fmt.Errorf("environment variable %s isn't set", c.config.APIKeyEnv)
+		}
+	}
+
+	if c.config.HTTP.Name == "" {
+		c.config.HTTP.Name = "llm"
+	}
+	if c.config.HTTP.Retry.MaxRetries == 0 {
+		c.config.HTTP.Retry.MaxRetries = defaultMaxRetries
+	}
+
+	c.instance = NewClient(c.config.BaseURL, apiKey)
+	c.instance.httpClient = httpclient.New(c.config.HTTP)
+	c.instance.model = c.config.Model
+	if c.config.System != "" {
+		c.instance.system = c.config.System
+	}
+	if c.config.Timeout > 0 {
+		c.instance.timeout = c.config.Timeout
+	}
+
+	return nil
+}
+
+func Get(ctx context.Context) *Client {
+	return instance.Get(ctx)
+}
+
+func (c *Component) Get(ctx context.Context) *Client {
+	return c.instance
+}
+
+func (c *Component) Close() error {
+
+	return nil
+}
+
+func (c *Component) BeforeInit() error {
+	return nil
+}
+
+func (c *Component) AfterInit(applicationContext *spark.ApplicationContext) error {
+	c.ctx = applicationContext
+
+	return c.Instantiate()
+}
+
+func (c *Component) BeforeStop() {
+	return
+}
+
+func (c *Component) AfterStop() {
+	_ = c.Close()
+
+	return
+}
@@ -0,0 +1,21 @@
+package llm
+
+import (
+	"time"
+
+	"github.com/www-xu/spark/httpclient"
+)
+
+type Config struct {
+	// BaseURL is the OpenAI-compatible API root, e.g. https://api.openai.com/v1 or http://localhost:11434/v1.
+	BaseURL string `mapstructure:"base_url"`
+	// APIKey is the key in plain text, prefer APIKeyEnv to keep keys out of config files.
+	APIKey    string        `mapstructure:"api_key"`
+	APIKeyEnv string        `mapstructure:"api_key_env"` // name of the environment variable holding the key
+	Model     string        `mapstructure:"model"`       // used when a request doesn't set one
+	System    string        `mapstructure:"system"`      // gen_ai.system of spans and metrics, defaults to openai
+	Timeout   time.Duration `mapstructure:"timeout"`     // timeout of blocking calls, defaults to 2 minutes
+	// HTTP configures the transport. Retries default to 3 so that rate limited calls are retried,
+	// set http.retry.max_retries to a negative value to disable them.
+	HTTP httpclient.Config `mapstructure:"http"`
+}
@@ -0,0 +1,172 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/www-xu/spark/httpclient"
+	"github.com/www-xu/spark/httpclient/sse"
+	"github.com/www-xu/spark/log"
+)
+
+var streamDone = []byte("[DONE]")
+
+// ChatCompletionStream reads the chunks of a streaming chat completion and assembles the message of
+// the first choice. It isn't safe for concurrent use.
+type ChatCompletionStream struct {
+	ctx      context.Context
+	body     io.ReadCloser
+	reader   *sse.Reader
+	op       *operation
+	finished bool
+
+	id            string
+	model         string
+	message       Message
+	finishReasons []string
+	usage         *Usage
+}
+
+// StreamChatCompletion sends the request with stream enabled and usage included in the last chunk.
+// The call is bound to ctx instead of the client timeout and callers must Close the stream.
+func (c *Client) StreamChatCompletion(ctx context.Context, request ChatCompletionRequest) (*ChatCompletionStream, error) {
+	request.Stream = true
+	if request.StreamOptions == nil {
+		request.StreamOptions = &StreamOptions{IncludeUsage: true}
+	}
+	if request.Model == "" {
+		request.Model = c.model
+	}
+
+	ctx, op := c.telemetry.start(ctx, c.system, &request)
+
+	responseBody, err := c.post(httpclient.WithTimeout(ctx, 0), request, "text/event-stream")
+	if err != nil {
+		op.end("", "", nil, nil, err)
+		log.WithContext(ctx).WithError(err).WithField("model", request.Model).Error("failed to stream chat completion")
+		return nil, err
+	}
+
+	return &ChatCompletionStream{
+		ctx:     ctx,
+		body:    responseBody,
+		reader:  sse.NewReader(responseBody),
+		op:      op,
+		message: Message{Role: RoleAssistant},
+	}, nil
+}
+
+// Next returns the next chunk. It returns io.EOF after [DONE] or when the server closes the stream,
+// an *APIError for error payloads and ctx.Err() after cancellation.
+func (s *ChatCompletionStream) Next() (*ChatCompletionChunk, error) {
+	for {
+		if s.finished {
+			return nil, io.EOF
+		}
+
+		data, err := s.reader.Next()
+		if err != nil {
+			if ctxErr := s.ctx.Err(); ctxErr != nil {
+				err = ctxErr
+			}
+			if errors.Is(err, io.EOF) {
+				s.finish(nil)
+			} else {
+				s.finish(err)
+			}
+			return nil, err
+		}
+		if data == nil {
+			continue
+		}
+		if bytes.Equal(data, streamDone) {
+			s.finish(nil)
+			return nil, io.EOF
+		}
+
+		var chunk struct {
+			ChatCompletionChunk
+			Error *APIError `json:"error"`
+		}
+		err = json.Unmarshal(data, &chunk)
+		if err != nil {
+			s.finish(err)
+			return nil, err
+		}
+		if chunk.Error != nil {
+			s.finish(chunk.Error)
+			return nil, chunk.Error
+		}
+
+		s.accumulate(&chunk.ChatCompletionChunk)
+
+		return &chunk.ChatCompletionChunk, nil
+	}
+}
+
+// Message returns the message of the first choice assembled from the chunks read so far,
+// including tool calls whose arguments have been streamed in pieces.
+func (s *ChatCompletionStream) Message() Message {
+	return s.message
+}
+
+// Usage returns the token usage, which is only known once the stream has ended.
+func (s *ChatCompletionStream) Usage() *Usage {
+	return s.usage
+}
+
+// Close ends the span of the call and closes the connection.
+func (s *ChatCompletionStream) Close() error {
+	s.finish(nil)
+	return s.body.Close()
+}
+
+func (s *ChatCompletionStream) finish(err error) {
+	s.finished = true
+	s.op.end(s.id, s.model, s.finishReasons, s.usage, err)
+}
+
+func (s *ChatCompletionStream) accumulate(chunk *ChatCompletionChunk) {
+	if chunk.ID != "" {
+		s.id = chunk.ID
+	}
+	if chunk.Model != "" {
+		s.model = chunk.Model
+	}
+	if chunk.Usage != nil {
+		s.usage = chunk.Usage
+	}
+
+	for _, choice := range chunk.Choices {
+		if choice.FinishReason != "" {
+			s.finishReasons = append(s.finishReasons, choice.FinishReason)
+		}
+		if choice.Index != 0 {
+			continue
+		}
+
+		s.message.Content += choice.Delta.Content
+		for _, delta := range choice.Delta.ToolCalls {
+			index := len(s.message.ToolCalls)
+			if delta.Index != nil {
+				index = *delta.Index
+			}
+			for len(s.message.ToolCalls) <= index {
+				s.message.ToolCalls = append(s.message.ToolCalls, ToolCall{})
+			}
+
+			toolCall := &s.message.ToolCalls[index]
+			if delta.ID != "" {
+				toolCall.ID = delta.ID
+			}
+			if delta.Type != "" {
+				toolCall.Type = delta.Type
+			}
+			toolCall.Function.Name += delta.Function.Name
+			toolCall.Function.Arguments += delta.Function.Arguments
+		}
+	}
+}
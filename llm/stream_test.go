@@ -0,0 +1,180 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// writeEvents writes the data of each event as a server-sent event.
+func writeEvents(w http.ResponseWriter, events ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, event := range events {
+		_, _ = io.WriteString(w, "data: "+event+"\n\n")
+	}
+}
+
+// readAll reads the stream until it ends and returns the content of the chunks.
+func readAll(t *testing.T, stream *ChatCompletionStream) (string, error) {
+	t.Helper()
+
+	var content strings.Builder
+	for {
+		chunk, err := stream.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return content.String(), nil
+			}
+			return content.String(), err
+		}
+		for _, choice := range chunk.Choices {
+			content.WriteString(choice.Delta.Content)
+		}
+	}
+}
+
+func TestStreamChatCompletion(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request, request ChatCompletionRequest) {
+		if !request.Stream || request.StreamOptions == nil || !request.StreamOptions.IncludeUsage {
+			t.Errorf("unexpected request %+v", request)
+		}
+		if r.Header.Get("Accept") != "text/event-stream" {
+			t.Errorf("Accept = %q", r.Header.Get("Accept"))
+		}
+
+		_, _ = io.WriteString(w, ": keep-alive\n\n")
+		writeEvents(w,
+			`{"id":"c1","model":"gpt-test","choices":[{"index":0,"delta":{"role":"assistant","content":"Hel"}}]}`,
+			`{"id":"c1","choices":[{"index":0,"delta":{"content":"lo"},"finish_reason":"stop"}]}`,
+			`{"id":"c1","choices":[],"usage":{"prompt_tokens":5,"completion_tokens":2,"total_tokens":7}}`,
+			`[DONE]`,
+			// nothing after [DONE] is read
+			`{"id":"c1","choices":[{"index":0,"delta":{"content":"!"}}]}`,
+		)
+	})
+
+	stream, err := client.StreamChatCompletion(context.Background(), ChatCompletionRequest{Messages: []Message{UserMessage("hi")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	content, err := readAll(t, stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "Hello" || stream.Message().Content != "Hello" || stream.Message().Role != RoleAssistant {
+		t.Errorf("content = %q, message = %+v", content, stream.Message())
+	}
+	if usage := stream.Usage(); usage == nil || usage.TotalTokens != 7 {
+		t.Errorf("usage = %+v", usage)
+	}
+
+	_, err = stream.Next()
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("err = %v, want io.EOF once the stream has ended", err)
+	}
+}
+
+func TestStreamChatCompletionToolCalls(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request, request ChatCompletionRequest) {
+		writeEvents(w,
+			`{"id":"c1","choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]}}]}`,
+			`{"id":"c1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}]}`,
+			`{"id":"c1","choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"call_2","type":"function","function":{"name":"get_time","arguments":"{}"}}]}}]}`,
+			`{"id":"c1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Paris\"}"}}]}}]}`,
+			`{"id":"c1","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+		)
+		// the server closes the stream without [DONE]
+	})
+
+	stream, err := client.StreamChatCompletion(context.Background(), ChatCompletionRequest{
+		Messages: []Message{UserMessage("weather in Paris?")},
+		Tools:    []Tool{FunctionTool("get_weather", "", nil), FunctionTool("get_time", "", nil)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	_, err = readAll(t, stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	toolCalls := stream.Message().ToolCalls
+	if len(toolCalls) != 2 {
+		t.Fatalf("tool calls = %+v, want 2", toolCalls)
+	}
+	if toolCalls[0].ID != "call_1" || toolCalls[0].Type != "function" || toolCalls[0].Function.Name != "get_weather" {
+		t.Errorf("first tool call = %+v", toolCalls[0])
+	}
+	var arguments struct {
+		City string `json:"city"`
+	}
+	err = toolCalls[0].DecodeArguments(&arguments)
+	if err != nil || arguments.City != "Paris" {
+		t.Errorf("arguments = %s, %v", toolCalls[0].Function.Arguments, err)
+	}
+	if toolCalls[1].ID != "call_2" || toolCalls[1].Function.Name != "get_time" || toolCalls[1].Function.Arguments != "{}" {
+		t.Errorf("second tool call = %+v", toolCalls[1])
+	}
+}
+
+func TestStreamChatCompletionError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request, request ChatCompletionRequest) {
+		writeEvents(w,
+			`{"id":"c1","choices":[{"index":0,"delta":{"content":"Hel"}}]}`,
+			`{"error":{"message":"The server had an error while processing your request.","type":"server_error"}}`,
+		)
+	})
+
+	stream, err := client.StreamChatCompletion(context.Background(), ChatCompletionRequest{Messages: []Message{UserMessage("hi")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	content, err := readAll(t, stream)
+	var apiError *APIError
+	if !errors.As(err, &apiError) || apiError.Type != "server_error" {
+		t.Fatalf("err = %v, want the error payload", err)
+	}
+	if content != "Hel" {
+		t.Errorf("content = %q, want the chunks before the error", content)
+	}
+
+	_, err = stream.Next()
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("err = %v, want io.EOF after the error", err)
+	}
+}
+
+func TestStreamChatCompletionCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request, request ChatCompletionRequest) {
+		writeEvents(w, `{"id":"c1","choices":[{"index":0,"delta":{"content":"Hel"}}]}`)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+
+	stream, err := client.StreamChatCompletion(ctx, ChatCompletionRequest{Messages: []Message{UserMessage("hi")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	_, err = stream.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	_, err = stream.Next()
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
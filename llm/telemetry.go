@@ -0,0 +1,130 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/www-xu/spark/llm"
+
+type telemetry struct {
+	tracer   trace.Tracer
+	tokens   metric.Int64Histogram
+	duration metric.Float64Histogram
+}
+
+func newTelemetry() *telemetry {
+	meter := otel.Meter(instrumentationName)
+	t := &telemetry{tracer: otel.Tracer(instrumentationName)}
+	t.tokens, _ = meter.Int64Histogram("gen_ai.client.token.usage",
+		metric.WithDescription("number of input and output tokens used"),
+		metric.WithUnit("{token}"),
+	)
+	t.duration, _ = meter.Float64Histogram("gen_ai.client.operation.duration",
+		metric.WithDescription("duration of chat completion calls"),
+		metric.WithUnit("s"),
+	)
+	return t
+}
+
+// operation tracks one chat completion call from the request until the full response has been read.
+type operation struct {
+	telemetry *telemetry
+	ctx       context.Context
+	span      trace.Span
+	start     time.Time
+	attrs     []attribute.KeyValue
+	ended     bool
+}
+
+func (t *telemetry) start(ctx context.Context, system string, request *ChatCompletionRequest) (context.Context, *operation) {
+	attrs := []attribute.KeyValue{
+		semconv.GenAIOperationNameChat,
+		semconv.GenAISystemKey.String(system),
+		semconv.GenAIRequestModel(request.Model),
+	}
+
+	ctx, span := t.tracer.Start(ctx, "chat "+request.Model,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	if request.MaxTokens > 0 {
+		span.SetAttributes(semconv.GenAIRequestMaxTokens(request.MaxTokens))
+	}
+	if request.Temperature != nil {
+		span.SetAttributes(semconv.GenAIRequestTemperature(*request.Temperature))
+	}
+
+	return ctx, &operation{
+		telemetry: t,
+		ctx:       ctx,
+		span:      span,
+		start:     time.Now(),
+		attrs:     attrs,
+	}
+}
+
+// end records the response, usage and err on the span and in the metrics. Only the first call has an effect.
+func (o *operation) end(id, model string, finishReasons []string, usage *Usage, err error) {
+	if o.ended {
+		return
+	}
+	o.ended = true
+
+	attrs := o.attrs
+	if model != "" {
+		attrs = append(attrs, semconv.GenAIResponseModel(model))
+		o.span.SetAttributes(semconv.GenAIResponseModel(model))
+	}
+	if id != "" {
+		o.span.SetAttributes(semconv.GenAIResponseID(id))
+	}
+	if len(finishReasons) > 0 {
+		o.span.SetAttributes(semconv.GenAIResponseFinishReasons(finishReasons...))
+	}
+
+	if usage != nil {
+		o.span.SetAttributes(
+			semconv.GenAIUsageInputTokens(usage.PromptTokens),
+			semconv.GenAIUsageOutputTokens(usage.CompletionTokens),
+		)
+		o.telemetry.tokens.Record(o.ctx, int64(usage.PromptTokens),
+			metric.WithAttributes(append(attrs, semconv.GenAITokenTypeInput)...))
+		o.telemetry.tokens.Record(o.ctx, int64(usage.CompletionTokens),
+			metric.WithAttributes(append(attrs, semconv.GenAITokenTypeOutput)...))
+	}
+
+	if err != nil {
+		attrs = append(attrs, semconv.ErrorTypeKey.String(errorType(err)))
+		o.span.RecordError(err)
+		o.span.SetStatus(codes.Error, err.Error())
+	}
+	o.telemetry.duration.Record(o.ctx, time.Since(o.start).Seconds(), metric.WithAttributes(attrs...))
+
+	o.span.End()
+}
+
+func errorType(err error) string {
+	var apiError *APIError
+	if errors.As(err, &apiError) {
+		if apiError.Type != "" {
+			return apiError.Type
+		}
+		if apiError.StatusCode > 0 {
+			return http.StatusText(apiError.StatusCode)
+		}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timeout"
+	}
+	return "_OTHER"
+}
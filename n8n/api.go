@@ -0,0 +1,312 @@
+package n8n
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/www-xu/spark"
+)
+
+const (
+	ExecutionStatusNew      = "new"
+	ExecutionStatusSuccess  = "success"
+	ExecutionStatusError    = "error"
+	ExecutionStatusWaiting  = "waiting"
+	ExecutionStatusRunning  = "running"
+	ExecutionStatusCanceled = "canceled"
+)
+
+const (
+	// ExecutionKeyField is the field of the webhook body that carries the key ExecuteWorkflow looks executions up by.
+	ExecutionKeyField = "execution_key"
+
+	defaultLookupInterval = 500 * time.Millisecond
+	defaultLookupTimeout  = 30 * time.Second
+	defaultLookupLimit    = 20
+	// lookupClockSkew widens the start time bound of the search, as the n8n server's clock may differ from ours
+	lookupClockSkew = time.Minute
+)
+
+// ErrExecutionNotFound is returned by ExecuteWorkflow when the workflow was started but its execution
+// couldn't be found within the lookup timeout, e.g. because the workflow doesn't save its executions.
+var ErrExecutionNotFound = errors.New("n8n execution not found")
+
+// ExecuteWorkflow starts a workflow through its webhook and returns the id of the execution.
+// The public REST API has no endpoint to start a workflow and webhooks don't return the execution id,
+// so the webhook body carries a random execution_key that is then searched for in the body received by
+// the webhook trigger of the executions started since the call. The webhook should respond immediately,
+// and the workflow must save its executions, including their progress to be found while still running.
+func (c *Client) ExecuteWorkflow(ctx context.Context, request *ExecuteWorkflowRequest) (string, error) {
+	if c.apiKey == "" {
+		return "", errors.New("n8n api key isn't configured")
+	}
+
+	key, err := newExecutionKey()
+	if err != nil {
+		return "", err
+	}
+
+	webhookPath := request.WebhookPath
+	if webhookPath == "" {
+		webhookPath = request.WorkflowID
+	}
+
+	since := time.Now().Add(-lookupClockSkew)
+	_, err = c.postWebhook(ctx, webhookPath, map[string]interface{}{
+		"environment":     spark.Env(),
+		"inputs":          request.Inputs,
+		ExecutionKeyField: key,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	interval := request.LookupInterval
+	if interval <= 0 {
+		interval = defaultLookupInterval
+	}
+	timeout := request.LookupTimeout
+	if timeout <= 0 {
+		timeout = defaultLookupTimeout
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		executionID, err := c.findExecution(lookupCtx, request.WorkflowID, key, since)
+		if err != nil && lookupCtx.Err() == nil {
+			return "", err
+		}
+		if executionID != "" {
+			return executionID, nil
+		}
+
+		select {
+		case <-lookupCtx.Done():
+			return "", fmt.Errorf("%w: workflow %s, %s %s: %w", ErrExecutionNotFound, request.WorkflowID, ExecutionKeyField, key, lookupCtx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// findExecution returns the id of the execution whose webhook received key, or an empty id. It pages
+// through the executions of the workflow from the latest back to the ones started before since.
+func (c *Client) findExecution(ctx context.Context, workflowID, key string, since time.Time) (string, error) {
+	cursor := ""
+	for {
+		executions, err := c.ListExecutions(ctx, &ListExecutionsRequest{
+			WorkflowID:  workflowID,
+			IncludeData: true,
+			Limit:       defaultLookupLimit,
+			Cursor:      cursor,
+		})
+		if err != nil {
+			return "", err
+		}
+
+		for _, execution := range executions.Data {
+			if execution.StartedAt != nil && execution.StartedAt.Before(since) {
+				return "", nil
+			}
+			if execution.Data != nil && receivedKey(execution.Data.ResultData.RunData, key) {
+				return execution.ID, nil
+			}
+		}
+
+		if executions.NextCursor == nil || *executions.NextCursor == "" {
+			return "", nil
+		}
+		cursor = *executions.NextCursor
+	}
+}
+
+// nodeRun is the part of a node run in RunData that holds the items the node output.
+type nodeRun struct {
+	Data struct {
+		Main [][]struct {
+			JSON struct {
+				Body map[string]any `json:"body"`
+			} `json:"json"`
+		} `json:"main"`
+	} `json:"data"`
+}
+
+// receivedKey reports whether a node, i.e. the webhook trigger, output a request body whose execution_key is key.
+func receivedKey(runData map[string]json.RawMessage, key string) bool {
+	for _, raw := range runData {
+		// skip the nodes that can't hold the key before decoding their output
+		if !bytes.Contains(raw, []byte(key)) {
+			continue
+		}
+
+		var runs []nodeRun
+		if json.Unmarshal(raw, &runs) != nil {
+			continue
+		}
+		for _, run := range runs {
+			for _, output := range run.Data.Main {
+				for _, item := range output {
+					if item.JSON.Body[ExecutionKeyField] == key {
+						return true
+					}
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+func newExecutionKey() (string, error) {
+	key := make([]byte, 16)
+	_, err := rand.Read(key)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(key), nil
+}
+
+// GetExecution returns an execution, includeData adds the node outputs of the run.
+func (c *Client) GetExecution(ctx context.Context, executionID string, includeData bool) (*Execution, error) {
+	query := url.Values{}
+	query.Set("includeData", strconv.FormatBool(includeData))
+
+	var response Execution
+	err := c.doAPI(ctx, http.MethodGet, "/api/v1/executions/"+url.PathEscape(executionID)+"?"+query.Encode(), nil, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// WaitExecution polls an execution every interval until it's no longer new, running or waiting.
+func (c *Client) WaitExecution(ctx context.Context, executionID string, interval time.Duration) (*Execution, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		execution, err := c.GetExecution(ctx, executionID, true)
+		if err != nil {
+			return nil, err
+		}
+		if execution.Finished || (execution.Status != ExecutionStatusNew && execution.Status != ExecutionStatusRunning && execution.Status != ExecutionStatusWaiting) {
+			return execution, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ListExecutions returns a page of executions, pass NextCursor of the previous page as Cursor to paginate.
+func (c *Client) ListExecutions(ctx context.Context, request *ListExecutionsRequest) (*ExecutionList, error) {
+	query := url.Values{}
+	if request.WorkflowID != "" {
+		query.Set("workflowId", request.WorkflowID)
+	}
+	if request.Status != "" {
+		query.Set("status", request.Status)
+	}
+	if request.ProjectID != "" {
+		query.Set("projectId", request.ProjectID)
+	}
+	if request.IncludeData {
+		query.Set("includeData", "true")
+	}
+	if request.Limit > 0 {
+		query.Set("limit", strconv.Itoa(request.Limit))
+	}
+	if request.Cursor != "" {
+		query.Set("cursor", request.Cursor)
+	}
+
+	var response ExecutionList
+	err := c.doAPI(ctx, http.MethodGet, "/api/v1/executions?"+query.Encode(), nil, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+// ListFailedExecutions returns a page of executions with status error.
+func (c *Client) ListFailedExecutions(ctx context.Context, workflowID string, limit int, cursor string) (*ExecutionList, error) {
+	return c.ListExecutions(ctx, &ListExecutionsRequest{
+		WorkflowID: workflowID,
+		Status:     ExecutionStatusError,
+		Limit:      limit,
+		Cursor:     cursor,
+	})
+}
+
+// RetryExecution retries a failed execution and returns the new execution. loadWorkflow retries
+// with the current version of the workflow instead of the one the execution ran with.
+func (c *Client) RetryExecution(ctx context.Context, executionID string, loadWorkflow bool) (*Execution, error) {
+	var response Execution
+	err := c.doAPI(ctx, http.MethodPost, "/api/v1/executions/"+url.PathEscape(executionID)+"/retry", map[string]bool{"loadWorkflow": loadWorkflow}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+}
+
+func (c *Client) doAPI(ctx context.Context, method, path string, requestBody, response any) error {
+	if c.apiKey == "" {
+		return errors.New("n8n api key isn't configured")
+	}
+
+	body, err := newJSONBody(requestBody)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s%s", c.host, path), body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+	if requestBody != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	request.Header.Set("X-N8N-API-KEY", c.apiKey)
+
+	responseBody, err := c.do(request)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(responseBody, response)
+}
+
+func newJSONBody(value any) (io.Reader, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	requestBytes, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(requestBytes), nil
+}
@@ -0,0 +1,232 @@
+package n8n
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testAPIKey = "n8n-api-test"
+
+// newTestClient returns a client of an n8n server handled by handler, which also checks the webhook auth
+// header and the api key of the REST API.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/webhook/") && r.Header.Get("X-Auth") != "token" {
+			t.Errorf("X-Auth = %q, want token", r.Header.Get("X-Auth"))
+		}
+		if strings.HasPrefix(r.URL.Path, "/api/v1/") && r.Header.Get("X-N8N-API-KEY") != testAPIKey {
+			t.Errorf("X-N8N-API-KEY = %q, want %s", r.Header.Get("X-N8N-API-KEY"), testAPIKey)
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return NewClient(server.URL, "X-Auth", "token").WithAPIKey(testAPIKey)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = io.WriteString(w, body)
+}
+
+// executionJSON is an execution whose webhook trigger received body, and whose Log node output logged.
+func executionJSON(id string, startedAt time.Time, body, logged string) string {
+	return fmt.Sprintf(`{"id":%q,"status":"running","startedAt":%q,"data":{"resultData":{"runData":{
+		"Webhook":[{"data":{"main":[[{"json":{"headers":{},"body":%s}}]]}}],
+		"Log":[{"data":{"main":[[{"json":{"message":%q}}]]}}]
+	}}}}`, id, startedAt.Format(time.RFC3339Nano), body, logged)
+}
+
+func TestExecuteWorkflow(t *testing.T) {
+	var key atomic.Value
+	var lookups atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/webhook/hook-1":
+			var body map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			key.Store(body[ExecutionKeyField])
+			if inputs, _ := body["inputs"].(map[string]any); inputs["order"] != "o1" {
+				t.Errorf("inputs = %v, want order o1", body["inputs"])
+			}
+			writeJSON(w, http.StatusOK, `{"message":"Workflow was started"}`)
+
+		case "/api/v1/executions":
+			query := r.URL.Query()
+			if query.Get("workflowId") != "wf-1" || query.Get("includeData") != "true" {
+				t.Errorf("unexpected query %s", r.URL.RawQuery)
+			}
+
+			now := time.Now()
+			key, _ := key.Load().(string)
+			if lookups.Add(1) == 1 {
+				// the execution isn't saved yet
+				writeJSON(w, http.StatusOK, `{"data":[],"nextCursor":null}`)
+				return
+			}
+			switch query.Get("cursor") {
+			case "":
+				// a newer execution only logs the key, e.g. one that was passed it as an input
+				writeJSON(w, http.StatusOK, `{"data":[`+
+					executionJSON("12", now, `{"execution_key":"other"}`, "retrying "+key)+`,`+
+					executionJSON("11", now, `{}`, "")+
+					`],"nextCursor":"page-2"}`)
+			case "page-2":
+				writeJSON(w, http.StatusOK, `{"data":[`+
+					executionJSON("10", now, fmt.Sprintf(`{"execution_key":%q}`, key), "")+
+					`],"nextCursor":null}`)
+			default:
+				t.Errorf("unexpected cursor %s", query.Get("cursor"))
+			}
+
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+	})
+
+	executionID, err := client.ExecuteWorkflow(context.Background(), &ExecuteWorkflowRequest{
+		WorkflowID:     "wf-1",
+		WebhookPath:    "hook-1",
+		Inputs:         map[string]interface{}{"order": "o1"},
+		LookupInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if executionID != "10" {
+		t.Errorf("execution id = %s, want 10", executionID)
+	}
+}
+
+func TestExecuteWorkflowNotFound(t *testing.T) {
+	var pages atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/webhook/wf-1" {
+			writeJSON(w, http.StatusOK, `{}`)
+			return
+		}
+
+		if r.URL.Query().Get("cursor") != "" {
+			t.Error("executions started before the call must not be paged through")
+		}
+		pages.Add(1)
+		writeJSON(w, http.StatusOK, `{"data":[`+
+			executionJSON("9", time.Now().Add(-time.Hour), `{}`, "")+
+			`],"nextCursor":"page-2"}`)
+	})
+
+	start := time.Now()
+	_, err := client.ExecuteWorkflow(context.Background(), &ExecuteWorkflowRequest{
+		WorkflowID:     "wf-1",
+		LookupInterval: 10 * time.Millisecond,
+		LookupTimeout:  100 * time.Millisecond,
+	})
+	if !errors.Is(err, ErrExecutionNotFound) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want ErrExecutionNotFound after the lookup timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("lookup took %s, want about the lookup timeout", elapsed)
+	}
+	if pages.Load() < 2 {
+		t.Errorf("executions searched %d times, want the lookup to poll", pages.Load())
+	}
+}
+
+func TestExecuteWorkflowRequiresAPIKey(t *testing.T) {
+	client := NewClient("http://127.0.0.1:0", "X-Auth", "token")
+
+	_, err := client.ExecuteWorkflow(context.Background(), &ExecuteWorkflowRequest{WorkflowID: "wf-1"})
+	if err == nil {
+		t.Error("want an error without an api key")
+	}
+}
+
+func TestWaitExecution(t *testing.T) {
+	var polls atomic.Int32
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/executions/10" || r.URL.Query().Get("includeData") != "true" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if polls.Add(1) < 3 {
+			writeJSON(w, http.StatusOK, `{"id":"10","status":"running"}`)
+			return
+		}
+		writeJSON(w, http.StatusOK, `{"id":"10","status":"error","finished":false,"data":{"resultData":{"error":{"message":"boom"}}}}`)
+	})
+
+	execution, err := client.WaitExecution(context.Background(), "10", 5*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if execution.Status != ExecutionStatusError || execution.Data.ResultData.Error.Message != "boom" || polls.Load() != 3 {
+		t.Errorf("unexpected execution %+v after %d polls", execution, polls.Load())
+	}
+}
+
+func TestRetryExecution(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/executions/10/retry" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		var body map[string]bool
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if !body["loadWorkflow"] {
+			t.Errorf("body = %v, want loadWorkflow", body)
+		}
+
+		writeJSON(w, http.StatusOK, `{"id":"11","retryOf":"10","status":"running"}`)
+	})
+
+	execution, err := client.RetryExecution(context.Background(), "10", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if execution.ID != "11" || execution.RetryOf == nil || *execution.RetryOf != "10" {
+		t.Errorf("unexpected execution %+v", execution)
+	}
+}
+
+func TestInvokeWorkflowAs(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/webhook/wf-1" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		writeJSON(w, http.StatusOK, `{"total":3,"items":["a","b","c"]}`)
+	})
+
+	type result struct {
+		Total int      `json:"total"`
+		Items []string `json:"items"`
+	}
+	response, err := InvokeWorkflowAs[result](context.Background(), client, "wf-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Total != 3 || len(response.Items) != 3 {
+		t.Errorf("unexpected response %+v", response)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusNotFound, `{"message":"Not Found"}`)
+	})
+
+	_, err := client.GetExecution(context.Background(), "404", false)
+	if err == nil {
+		t.Error("want an error for a 404 response")
+	}
+}
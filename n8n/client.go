@@ -8,14 +8,19 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/www-xu/spark"
+	"github.com/www-xu/spark/httpclient"
 )
 
+const defaultTimeout = time.Minute
+
 type Client struct {
 	host            string
 	authHeaderKey   string
 	authHeaderValue string
+	apiKey          string
 	httpClient      *http.Client
 }
 
@@ -24,15 +29,56 @@ func NewClient(host, authHeaderKey, authHeaderValue string) *Client {
 		host:            host,
 		authHeaderKey:   authHeaderKey,
 		authHeaderValue: authHeaderValue,
-		httpClient:      &http.Client{},
+		httpClient:      httpclient.New(httpclient.Config{Name: "n8n", Timeout: defaultTimeout}),
 	}
 }
 
+// WithAPIKey returns a copy of the client that calls the REST API with the given key.
+func (c *Client) WithAPIKey(apiKey string) *Client {
+	client := *c
+	client.apiKey = apiKey
+	return &client
+}
+
 func (c *Client) InvokeWorkflow(ctx context.Context, workflowId string, inputs map[string]interface{}) (response *InvokeWorkflowResponse, err error) {
-	requestBody := map[string]interface{}{
+	responseBody, err := c.invokeWebhook(ctx, workflowId, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(responseBody, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// InvokeWorkflowAs invokes the workflow webhook like Client.InvokeWorkflow and decodes the response into T,
+// which should mirror what the workflow's Respond to Webhook node returns.
+func InvokeWorkflowAs[T any](ctx context.Context, client *Client, workflowId string, inputs map[string]interface{}) (*T, error) {
+	responseBody, err := client.invokeWebhook(ctx, workflowId, inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	var response T
+	err = json.Unmarshal(responseBody, &response)
+	if err != nil {
+		return nil, fmt.Errorf("decode n8n workflow %s response: %w", workflowId, err)
+	}
+
+	return &response, nil
+}
+
+func (c *Client) invokeWebhook(ctx context.Context, workflowId string, inputs map[string]interface{}) ([]byte, error) {
+	return c.postWebhook(ctx, workflowId, map[string]interface{}{
 		"environment": spark.Env(),
 		"inputs":      inputs,
-	}
+	})
+}
+
+func (c *Client) postWebhook(ctx context.Context, workflowId string, requestBody map[string]interface{}) ([]byte, error) {
 	requestBytes, err := json.Marshal(requestBody)
 	if err != nil {
 		return nil, err
@@ -46,10 +92,15 @@ func (c *Client) InvokeWorkflow(ctx context.Context, workflowId string, inputs m
 	request.Header.Set("Content-Type", "application/json")
 	request.Header.Set(c.authHeaderKey, c.authHeaderValue)
 
+	return c.do(request)
+}
+
+func (c *Client) do(request *http.Request) ([]byte, error) {
 	rawResponse, err := c.httpClient.Do(request)
 	if err != nil {
 		return nil, err
 	}
+	defer rawResponse.Body.Close()
 
 	responseBody, err := io.ReadAll(rawResponse.Body)
 	if err != nil {
@@ -60,10 +111,5 @@ func (c *Client) InvokeWorkflow(ctx context.Context, workflowId string, inputs m
 		return nil, errors.New(fmt.Sprintf("[%s] | %s", rawResponse.Status, string(responseBody)))
 	}
 
-	err = json.Unmarshal(responseBody, &response)
-	if err != nil {
-		return nil, err
-	}
-
-	return response, nil
+	return responseBody, nil
 }
@@ -3,17 +3,24 @@ package n8n
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
 	"github.com/www-xu/spark"
+	"github.com/www-xu/spark/httpclient"
+	"github.com/www-xu/spark/webhook"
 )
 
 type Component struct {
 	ctx      *spark.ApplicationContext
 	config   *Config
 	instance *Client
+	webhook  *webhook.Receiver
 }
 
 func NewComponent() *Component {
-	return &Component{}
+	return &Component{
+		webhook: newReceiver(),
+	}
 }
 
 var instance *Component
@@ -37,6 +44,28 @@ func (c *Component) Instantiate() error {
 
 	c.instance = NewClient(c.config.Host, c.config.AuthHeaderKey, c.config.AuthHeaderValue)
 
+	if c.config.HTTP.Name == "" {
+		c.config.HTTP.Name = "n8n"
+	}
+	if c.config.HTTP.Timeout <= 0 {
+		c.config.HTTP.Timeout = defaultTimeout
+	}
+	c.instance.httpClient = httpclient.New(c.config.HTTP)
+
+	c.instance.apiKey = c.config.APIKey
+	if c.config.APIKeyEnv != "" {
+		var ok bool
+		c.instance.apiKey, ok = os.LookupEnv(c.config.APIKeyEnv)
+		if !ok || c.instance.apiKey == "" {
+			return fmt.Errorf("environment variable %s isn't set", c.config.APIKeyEnv)
+		}
+	}
+
+	err = c.webhook.Configure(c.config.Webhook)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -1,7 +1,13 @@
 package n8n
 
+import "github.com/www-xu/spark/httpclient"
+
 type Config struct {
-	Host            string `mapstructure:"host"`
-	AuthHeaderKey   string `mapstructure:"auth_header_key"`
-	AuthHeaderValue string `mapstructure:"auth_header_value"`
+	Host            string            `mapstructure:"host"`
+	AuthHeaderKey   string            `mapstructure:"auth_header_key"`
+	AuthHeaderValue string            `mapstructure:"auth_header_value"`
+	APIKey          string            `mapstructure:"api_key"`     // key of the public REST API
+	APIKeyEnv       string            `mapstructure:"api_key_env"` // name of the environment variable holding the key, preferred over api_key
+	HTTP            httpclient.Config `mapstructure:"http"`        // http.timeout defaults to 1 minute
+	Webhook         WebhookConfig     `mapstructure:"webhook"`
 }
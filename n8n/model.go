@@ -1,5 +1,69 @@
 package n8n
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type InvokeWorkflowResponse struct {
 	Data map[string]interface{} `json:"data"`
 }
+
+type Execution struct {
+	ID             string         `json:"id"`
+	Finished       bool           `json:"finished"`
+	Mode           string         `json:"mode"`
+	RetryOf        *string        `json:"retryOf"`
+	RetrySuccessID *string        `json:"retrySuccessId"`
+	StartedAt      *time.Time     `json:"startedAt"`
+	StoppedAt      *time.Time     `json:"stoppedAt"`
+	WaitTill       *time.Time     `json:"waitTill"`
+	WorkflowID     string         `json:"workflowId"`
+	Status         string         `json:"status"`
+	Data           *ExecutionData `json:"data"`
+	CustomData     map[string]any `json:"customData"`
+}
+
+// ExecutionData is only set when the execution is fetched with includeData.
+type ExecutionData struct {
+	ResultData ExecutionResultData `json:"resultData"`
+}
+
+type ExecutionResultData struct {
+	Error            *ExecutionError `json:"error"`
+	LastNodeExecuted string          `json:"lastNodeExecuted"`
+	// RunData holds the runs of every node keyed by node name, left raw as its shape depends on the node.
+	RunData map[string]json.RawMessage `json:"runData"`
+}
+
+type ExecutionError struct {
+	Message     string `json:"message"`
+	Description string `json:"description"`
+	Name        string `json:"name"`
+	Node        *struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	} `json:"node"`
+}
+
+type ExecuteWorkflowRequest struct {
+	WorkflowID     string // id of the workflow in the REST API
+	WebhookPath    string // path of the workflow's webhook trigger, defaults to WorkflowID
+	Inputs         map[string]interface{}
+	LookupInterval time.Duration // how often the executions are searched after the webhook call, defaults to 500ms
+	LookupTimeout  time.Duration // how long the execution is searched for before ErrExecutionNotFound, defaults to 30s
+}
+
+type ListExecutionsRequest struct {
+	WorkflowID  string
+	Status      string // success, error, waiting, running or canceled
+	ProjectID   string
+	IncludeData bool
+	Limit       int
+	Cursor      string
+}
+
+type ExecutionList struct {
+	Data       []Execution `json:"data"`
+	NextCursor *string     `json:"nextCursor"`
+}
@@ -0,0 +1,76 @@
+package n8n
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+	"github.com/www-xu/spark/webhook"
+)
+
+const SignatureHeader = "X-N8N-Signature"
+
+// WebhookConfig configures the receiver of n8n callbacks, dedupe redis needs webhook/redisdedupe to be imported.
+type WebhookConfig = webhook.Config
+
+// CallbackEvent is the payload n8n flows post back to us. The flow signs the raw body with
+// HMAC-SHA256 and sends the hex digest in the X-N8N-Signature header, optionally prefixed with sha256=.
+type CallbackEvent struct {
+	Event       string          `json:"event"`
+	ExecutionID string          `json:"execution_id"`
+	WorkflowID  string          `json:"workflow_id"`
+	Data        json.RawMessage `json:"data"`
+}
+
+// EventHandler handles one callback event type, returning an error responds 500 so that n8n may retry.
+type EventHandler func(ctx context.Context, event *CallbackEvent) error
+
+func newReceiver() *webhook.Receiver {
+	return webhook.NewReceiver(webhook.Options{
+		Name:            "n8n",
+		SignatureHeader: SignatureHeader,
+		IDField:         "execution_id",
+		KeyPrefix:       "n8n:webhook:",
+		SpanFields:      []string{"workflow_id"},
+	})
+}
+
+// OnEvent registers the handler of a callback event type.
+func OnEvent(event string, handler EventHandler) {
+	instance.OnEvent(event, handler)
+}
+
+func (c *Component) OnEvent(event string, handler EventHandler) {
+	c.webhook.On(event, func(ctx context.Context, body []byte) error {
+		var callback CallbackEvent
+		err := webhook.Decode(body, &callback)
+		if err != nil {
+			return err
+		}
+		return handler(ctx, &callback)
+	})
+}
+
+// On registers a handler that receives the data of the event decoded into T.
+// Data that can't be decoded is rejected with 400 without calling fn.
+func On[T any](event string, fn func(ctx context.Context, event *CallbackEvent, data T) error) {
+	OnEvent(event, func(ctx context.Context, event *CallbackEvent) error {
+		var data T
+		err := webhook.Decode(event.Data, &data)
+		if err != nil {
+			return err
+		}
+		return fn(ctx, event, data)
+	})
+}
+
+// Webhook returns a gin handler that verifies the signature of n8n callbacks, drops events of
+// executions already handled, answers 409 to retries of an execution still being handled and
+// dispatches the rest to the handlers registered with OnEvent.
+func Webhook() gin.HandlerFunc {
+	return instance.Webhook()
+}
+
+func (c *Component) Webhook() gin.HandlerFunc {
+	return c.webhook.GinHandler()
+}
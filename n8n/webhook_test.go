@@ -0,0 +1,86 @@
+package n8n
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testSecret = "s3cret"
+
+// newTestWebhook gives the package component a fresh receiver, so that OnEvent and On register on it.
+func newTestWebhook(t *testing.T) *gin.Engine {
+	t.Helper()
+
+	instance.webhook = newReceiver()
+	err := instance.webhook.Configure(WebhookConfig{Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/n8n/callback", Webhook())
+
+	return engine
+}
+
+func postCallback(engine *gin.Engine, body, signature string) int {
+	request := httptest.NewRequest(http.MethodPost, "/n8n/callback", strings.NewReader(body))
+	request.Header.Set(SignatureHeader, signature)
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+	return recorder.Code
+}
+
+func sign(body string) string {
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhook(t *testing.T) {
+	engine := newTestWebhook(t)
+
+	type finished struct {
+		Rows int `json:"rows"`
+	}
+	var received []string
+	On("finished", func(ctx context.Context, event *CallbackEvent, data finished) error {
+		received = append(received, fmt.Sprintf("%s/%s/%d", event.ExecutionID, event.WorkflowID, data.Rows))
+		return nil
+	})
+
+	body := `{"event":"finished","execution_id":"e1","workflow_id":"w1","data":{"rows":3}}`
+	if status := postCallback(engine, body, sign(body)); status != http.StatusOK {
+		t.Errorf("status = %d, want 200", status)
+	}
+	if status := postCallback(engine, body, sign(body)); status != http.StatusOK {
+		t.Errorf("duplicate: status = %d, want 200", status)
+	}
+	if status := postCallback(engine, body, sign(body+" ")); status != http.StatusUnauthorized {
+		t.Errorf("bad signature: status = %d, want 401", status)
+	}
+
+	invalid := `{"event":"finished","execution_id":"e2","data":{"rows":"many"}}`
+	if status := postCallback(engine, invalid, sign(invalid)); status != http.StatusBadRequest {
+		t.Errorf("invalid data: status = %d, want 400", status)
+	}
+
+	missing := `{"event":"finished","workflow_id":"w1"}`
+	if status := postCallback(engine, missing, sign(missing)); status != http.StatusBadRequest {
+		t.Errorf("missing execution_id: status = %d, want 400", status)
+	}
+
+	if len(received) != 1 || received[0] != "e1/w1/3" {
+		t.Errorf("received = %v, want [e1/w1/3]", received)
+	}
+}
@@ -0,0 +1,97 @@
+package rabbitmq
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec 负责消息体的编解码，ContentType 会写入消息的 content_type header，消费时据此选择 codec
+type Codec interface {
+	ContentType() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec 使用 encoding/json 编解码
+type JSONCodec struct{}
+
+func (JSONCodec) ContentType() string { return "application/json" }
+
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// MsgpackCodec 使用 msgpack 编解码，比 JSON 更紧凑
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) ContentType() string { return "application/msgpack" }
+
+func (MsgpackCodec) Marshal(v any) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (MsgpackCodec) Unmarshal(data []byte, v any) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+// ProtobufCodec 编解码 proto.Message，类型参数通常是生成的消息指针类型，例如 *pb.OrderCreated
+type ProtobufCodec struct{}
+
+func (ProtobufCodec) ContentType() string { return "application/protobuf" }
+
+func (ProtobufCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("protobuf codec: %T isn't a proto.Message", v)
+	}
+	return proto.Marshal(m)
+}
+
+func (ProtobufCodec) Unmarshal(data []byte, v any) error {
+	if m, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(data, m)
+	}
+
+	// Handler[*pb.X] 传入的是 **pb.X，需要先分配消息
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && rv.Elem().Kind() == reflect.Pointer {
+		if rv.Elem().IsNil() {
+			rv.Elem().Set(reflect.New(rv.Elem().Type().Elem()))
+		}
+		if m, ok := rv.Elem().Interface().(proto.Message); ok {
+			return proto.Unmarshal(data, m)
+		}
+	}
+
+	return fmt.Errorf("protobuf codec: %T isn't a proto.Message", v)
+}
+
+var codecs sync.Map // content type -> Codec
+
+func init() {
+	RegisterCodec(JSONCodec{})
+	RegisterCodec(MsgpackCodec{})
+	RegisterCodec(ProtobufCodec{})
+}
+
+// RegisterCodec 注册自定义 codec，消费时按消息的 content_type 查找
+func RegisterCodec(codec Codec) {
+	codecs.Store(codec.ContentType(), codec)
+}
+
+func codecFor(contentType string) (Codec, bool) {
+	codec, ok := codecs.Load(contentType)
+	if !ok {
+		return nil, false
+	}
+	return codec.(Codec), true
+}
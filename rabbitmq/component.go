@@ -3,20 +3,30 @@ package rabbitmq
 import (
 	"context"
 	"errors"
+	"sync"
 
 	"github.com/google/uuid"
 	"github.com/www-xu/spark"
 
 	"github.com/ThreeDotsLabs/watermill"
 	watermillAmqp "github.com/ThreeDotsLabs/watermill-amqp/v3/pkg/amqp"
+	"github.com/ThreeDotsLabs/watermill/message"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 type Component struct {
-	ctx         *spark.ApplicationContext
-	config      *Config
-	publishers  map[string]*watermillAmqp.Publisher  // exchange name -> publisher
-	subscribers map[string]*watermillAmqp.Subscriber // exchange name -> subscriber
+	ctx            *spark.ApplicationContext
+	config         *Config
+	logger         watermill.LoggerAdapter
+	connection     *watermillAmqp.ConnectionWrapper
+	publishers     map[string]*watermillAmqp.Publisher  // exchange name -> publisher
+	subscribers    map[string]*watermillAmqp.Subscriber // exchange name -> subscriber
+	delayPublisher *watermillAmqp.Publisher
+	delayQueues    sync.Map // 延迟队列名 -> 上次声明的时间
+	inflight       sync.Map // message UUID -> *pendingPublish，等待确认的发布
+	handlers       []handlerRegistration
+	router         *message.Router
+	outbox         *outbox
 }
 
 func NewComponent() *Component {
@@ -41,36 +51,35 @@ func (c *Component) Instantiate() error {
 		return errors.New("rabbitmq config isn't found")
 	}
 
+	// 所有 exchange 的 publisher 和 subscriber 共享同一个连接，断开后自动重连
+	c.logger = newLogAdapter()
+	c.connection, err = watermillAmqp.NewConnection(watermillAmqp.ConnectionConfig{
+		AmqpURI:   c.config.Uri,
+		Reconnect: c.reconnectConfig(),
+	}, c.logger)
+	if err != nil {
+		return err
+	}
+
+	// 声明 queues 配置中的 queue 及绑定
+	err = c.declareTopology()
+	if err != nil {
+		return err
+	}
+
 	// 初始化 publishers 和 subscribers maps
 	c.publishers = make(map[string]*watermillAmqp.Publisher)
 	c.subscribers = make(map[string]*watermillAmqp.Subscriber)
 
-	// 为每个 exchange 创建独立的 publisher 和 subscriber
+	// 为每个 exchange 创建独立的 publisher 和 subscriber，各自使用共享连接上的 channel
 	// watermillAmqp 会在创建时自动声明 exchange
 	for exchangeName, exchangeConfig := range c.config.Exchanges {
 		// 创建针对该 exchange 的配置
 		amqpConfig := watermillAmqp.NewDurableQueueConfig(c.config.Uri)
-		amqpConfig.Marshaler = watermillAmqp.DefaultMarshaler{
-			PostprocessPublishing: func(publishing amqp.Publishing) amqp.Publishing {
-				var messageID string = uuid.New().String()
-				if value, ok := publishing.Headers[watermillAmqp.DefaultMessageUUIDHeaderKey]; ok {
-					if uuid, ok := value.(string); ok {
-						messageID = uuid
-					}
-				}
-				publishing.MessageId = messageID
-				return publishing
-			},
-		}
+		amqpConfig.Marshaler = newMarshaler()
 
 		// 为这个 exchange 配置 routing key 生成器
-		amqpConfig.Publish.GenerateRoutingKey = func(topic string) string {
-			if routingKey, ok := c.config.RoutingKeys[topic]; ok {
-				return routingKey
-			}
-			// 如果没有配置 routing key，默认使用 topic 名称
-			return topic
-		}
+		amqpConfig.Publish.GenerateRoutingKey = c.routingKey
 
 		// 固定返回当前 exchange 的名称
 		currentExchangeName := exchangeName
@@ -84,28 +93,95 @@ func (c *Component) Instantiate() error {
 		amqpConfig.Exchange.Durable = exchangeConfig.Durable
 		amqpConfig.Exchange.AutoDeleted = exchangeConfig.AutoDeleted
 		amqpConfig.Exchange.Arguments = exchangeConfig.Args
+		amqpConfig.TopologyBuilder = &topologyBuilder{queues: c.config.Queues}
+		amqpConfig.Publish.ConfirmDelivery = c.config.Publish.Confirm
+		amqpConfig.Publish.ChannelPoolSize = c.config.Publish.ChannelPoolSize
 
 		// 创建 publisher，watermillAmqp 会自动声明 exchange
-		publisher, err := watermillAmqp.NewPublisher(
-			amqpConfig,
-			watermill.NewStdLogger(spark.Env() != spark.Prod, spark.Env() != spark.Prod),
-		)
+		publisher, err := watermillAmqp.NewPublisherWithConnection(amqpConfig, c.logger, c.connection)
 		if err != nil {
 			return err
 		}
 		c.publishers[exchangeName] = publisher
 
 		// 创建 subscriber，watermillAmqp 会自动声明 exchange
-		subscriber, err := watermillAmqp.NewSubscriber(
-			amqpConfig,
-			watermill.NewStdLogger(false, false),
-		)
+		subscriber, err := watermillAmqp.NewSubscriberWithConnection(amqpConfig, c.logger, c.connection)
 		if err != nil {
 			return err
 		}
 		c.subscribers[exchangeName] = subscriber
 	}
 
+	// 延迟消息通过默认 exchange 直接投递到延迟队列
+	delayConfig := watermillAmqp.NewDurableQueueConfig(c.config.Uri)
+	delayConfig.Marshaler = newMarshaler()
+	delayConfig.Publish.ConfirmDelivery = c.config.Publish.Confirm
+	delayConfig.Publish.ChannelPoolSize = c.config.Publish.ChannelPoolSize
+	c.delayPublisher, err = watermillAmqp.NewPublisherWithConnection(delayConfig, c.logger, c.connection)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func newMarshaler() watermillAmqp.Marshaler {
+	return tracingMarshaler{
+		DefaultMarshaler: watermillAmqp.DefaultMarshaler{
+			PostprocessPublishing: func(publishing amqp.Publishing) amqp.Publishing {
+				var messageID string = uuid.New().String()
+				if value, ok := publishing.Headers[watermillAmqp.DefaultMessageUUIDHeaderKey]; ok {
+					if uuid, ok := value.(string); ok {
+						messageID = uuid
+					}
+				}
+				publishing.MessageId = messageID
+				if contentType, ok := publishing.Headers[ContentTypeHeader].(string); ok {
+					publishing.ContentType = contentType
+				}
+				return publishing
+			},
+		},
+	}
+}
+
+func (c *Component) routingKey(topic string) string {
+	if routingKey, ok := c.config.RoutingKeys[topic]; ok {
+		return routingKey
+	}
+	// 如果没有配置 routing key，默认使用 topic 名称
+	return topic
+}
+
+func (c *Component) reconnectConfig() *watermillAmqp.ReconnectConfig {
+	config := watermillAmqp.DefaultReconnectConfig()
+	if c.config.Reconnect.InitialInterval > 0 {
+		config.BackoffInitialInterval = c.config.Reconnect.InitialInterval
+	}
+	if c.config.Reconnect.MaxInterval > 0 {
+		config.BackoffMaxInterval = c.config.Reconnect.MaxInterval
+	}
+	if c.config.Reconnect.Multiplier > 0 {
+		config.BackoffMultiplier = c.config.Reconnect.Multiplier
+	}
+	if c.config.Reconnect.RandomizationFactor > 0 {
+		config.BackoffRandomizationFactor = c.config.Reconnect.RandomizationFactor
+	}
+	return config
+}
+
+// Health 返回连接状态，连接断开重连期间返回错误
+func Health(ctx context.Context) error {
+	return instance.Health(ctx)
+}
+
+func (c *Component) Health(ctx context.Context) error {
+	if c.connection == nil {
+		return errors.New("rabbitmq connection isn't initialized")
+	}
+	if !c.connection.IsConnected() {
+		return errors.New("rabbitmq connection is disconnected")
+	}
 	return nil
 }
 
@@ -124,6 +200,12 @@ func (c *Component) Close() error {
 	for _, publisher := range c.publishers {
 		_ = publisher.Close()
 	}
+	if c.delayPublisher != nil {
+		_ = c.delayPublisher.Close()
+	}
+	if c.connection != nil {
+		_ = c.connection.Close()
+	}
 
 	return nil
 }
@@ -133,7 +215,12 @@ func (c *Component) BeforeInit() error { return nil }
 func (c *Component) AfterInit(applicationContext *spark.ApplicationContext) error {
 	c.ctx = applicationContext
 
-	return c.Instantiate()
+	err := c.Instantiate()
+	if err != nil {
+		return err
+	}
+
+	return c.startRouter()
 }
 
 func GetPublisher(exchangeName string) (*watermillAmqp.Publisher, error) {
@@ -162,5 +249,11 @@ func (c *Component) GetSubscriber(exchangeName string) (*watermillAmqp.Subscribe
 	return subscriber, nil
 }
 
-func (c *Component) BeforeStop() {}
-func (c *Component) AfterStop()  {}
+func (c *Component) BeforeStop() {
+	c.stopOutbox()
+	c.stopRouter()
+}
+
+func (c *Component) AfterStop() {
+	_ = c.Close()
+}
@@ -1,5 +1,7 @@
 package rabbitmq
 
+import "time"
+
 type ExchangeConfig struct {
 	Type        string         `mapstructure:"type"`
 	Durable     bool           `mapstructure:"durable"`
@@ -7,8 +9,67 @@ type ExchangeConfig struct {
 	Args        map[string]any `mapstructure:"args"` // exchange type 需要的参数
 }
 
+type BindingConfig struct {
+	Exchange    string         `mapstructure:"exchange"`
+	RoutingKeys []string       `mapstructure:"routing_keys"` // topic exchange 支持 * 和 # 通配
+	Args        map[string]any `mapstructure:"args"`
+}
+
+type QueueConfig struct {
+	Type                 string          `mapstructure:"type"` // classic、quorum 或 stream，为空使用 broker 默认值
+	Durable              bool            `mapstructure:"durable"`
+	AutoDelete           bool            `mapstructure:"auto_delete"`
+	Exclusive            bool            `mapstructure:"exclusive"`
+	MessageTTL           time.Duration   `mapstructure:"message_ttl"`
+	MaxLength            int64           `mapstructure:"max_length"`
+	DeadLetterExchange   string          `mapstructure:"dead_letter_exchange"`
+	DeadLetterRoutingKey string          `mapstructure:"dead_letter_routing_key"`
+	Args                 map[string]any  `mapstructure:"args"` // 其他 x- 参数
+	Bindings             []BindingConfig `mapstructure:"bindings"`
+}
+
+type RetryConfig struct {
+	MaxRetries      int           `mapstructure:"max_retries"`
+	InitialInterval time.Duration `mapstructure:"initial_interval"`
+	MaxInterval     time.Duration `mapstructure:"max_interval"`
+	Multiplier      float64       `mapstructure:"multiplier"`
+}
+
+type ConsumerConfig struct {
+	Concurrency  int           `mapstructure:"concurrency"`   // 每个 handler 的并发消费者数量
+	CloseTimeout time.Duration `mapstructure:"close_timeout"` // 停止时等待处理中消息的时间
+	Retry        RetryConfig   `mapstructure:"retry"`
+	PoisonTopic  string        `mapstructure:"poison_topic"` // 重试耗尽的消息发送到同一 exchange 的该 topic，为空则不启用
+}
+
+type ReconnectConfig struct {
+	InitialInterval     time.Duration `mapstructure:"initial_interval"`
+	MaxInterval         time.Duration `mapstructure:"max_interval"`
+	Multiplier          float64       `mapstructure:"multiplier"`
+	RandomizationFactor float64       `mapstructure:"randomization_factor"`
+}
+
+type PublishConfig struct {
+	Confirm         bool          `mapstructure:"confirm"`           // 开启 publisher confirms，等待 broker 确认后才返回
+	ConfirmTimeout  time.Duration `mapstructure:"confirm_timeout"`   // 等待确认的超时时间
+	ChannelPoolSize int           `mapstructure:"channel_pool_size"` // 每个 publisher 复用的 channel 数量，为 0 时每次发布新建 channel
+}
+
+type OutboxConfig struct {
+	Table        string        `mapstructure:"table"`
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	BatchSize    int           `mapstructure:"batch_size"`
+	MaxAttempts  int           `mapstructure:"max_attempts"`  // 投递失败达到该次数后标记为 dead 不再重试，默认 10
+	LeaseTimeout time.Duration `mapstructure:"lease_timeout"` // 领取的消息在该时长内不会被其他实例领取，超时未发布完的消息留给下次领取，默认 1 分钟
+}
+
 type Config struct {
 	Uri         string                    `mapstructure:"uri"`
+	Reconnect   ReconnectConfig           `mapstructure:"reconnect"`    // 所有 publisher 和 subscriber 共享一个连接，断开后按退避重连
 	Exchanges   map[string]ExchangeConfig `mapstructure:"exchanges"`    // key 是 exchange name
 	RoutingKeys map[string]string         `mapstructure:"routing_keys"` // topic -> routing key
+	Queues      map[string]QueueConfig    `mapstructure:"queues"`       // key 是 queue name，handler 的 topic 与之相同时直接使用
+	Consumer    ConsumerConfig            `mapstructure:"consumer"`
+	Publish     PublishConfig             `mapstructure:"publish"`
+	Outbox      OutboxConfig              `mapstructure:"outbox"`
 }
@@ -0,0 +1,73 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	watermillAmqp "github.com/ThreeDotsLabs/watermill-amqp/v3/pkg/amqp"
+	"github.com/ThreeDotsLabs/watermill/message"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	// delayedMessageExchangeType 是 rabbitmq_delayed_message_exchange 插件提供的 exchange 类型
+	delayedMessageExchangeType = "x-delayed-message"
+	delayHeader                = "x-delay"
+	// delayQueueRedeclareInterval 是发布延迟消息时重新声明延迟队列的最长间隔，声明会重置 x-expires 的计时
+	delayQueueRedeclareInterval = time.Minute
+)
+
+// delayTarget 返回延迟消息实际使用的 publisher 和 topic。
+// exchange 类型为 x-delayed-message 时通过插件的 x-delay header 延迟，
+// 否则投递到按 exchange、routing key 和延迟时长声明的 TTL 队列，过期后经 dead letter 转发回原 exchange。
+func (c *Component) delayTarget(exchangeName, topic string, msg *message.Message, delay time.Duration) (*watermillAmqp.Publisher, string, error) {
+	if c.config.Exchanges[exchangeName].Type == delayedMessageExchangeType {
+		publisher, err := c.GetPublisher(exchangeName)
+		if err != nil {
+			return nil, "", err
+		}
+		if msg.Metadata == nil {
+			msg.Metadata = message.Metadata{}
+		}
+		msg.Metadata.Set(delayHeader, strconv.FormatInt(delay.Milliseconds(), 10))
+		return publisher, topic, nil
+	}
+
+	queueName, err := c.declareDelayQueue(exchangeName, c.routingKey(topic), delay)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return c.delayPublisher, queueName, nil
+}
+
+// declareDelayQueue 声明延迟队列，同一延迟时长的消息在一个队列中按顺序过期。
+// 队列设置 x-expires，不再使用后自动删除。发布消息不会重置空闲计时，所以发布时至少每隔
+// delayQueueRedeclareInterval 重新声明一次，x-expires 比 TTL 多出两个间隔，队列删除时最后一条消息已经过期转发。
+func (c *Component) declareDelayQueue(exchangeName, routingKey string, delay time.Duration) (string, error) {
+	ttl := delay.Milliseconds()
+	queueName := fmt.Sprintf("%s.%s.delay.%d", exchangeName, routingKey, ttl)
+	if declaredAt, ok := c.delayQueues.Load(queueName); ok && time.Since(declaredAt.(time.Time)) < delayQueueRedeclareInterval {
+		return queueName, nil
+	}
+
+	// 在声明之前取时间，实际重置计时的时刻只会更晚
+	declaredAt := time.Now()
+	err := c.withChannel(func(channel *amqp.Channel) error {
+		_, err := channel.QueueDeclare(queueName, true, false, false, false, amqp.Table{
+			amqp.QueueMessageTTLArg:     ttl,
+			amqp.QueueTTLArg:            ttl + (2 * delayQueueRedeclareInterval).Milliseconds(),
+			"x-dead-letter-exchange":    exchangeName,
+			"x-dead-letter-routing-key": routingKey,
+		})
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("declare delay queue %s: %w", queueName, err)
+	}
+
+	c.delayQueues.Store(queueName, declaredAt)
+
+	return queueName, nil
+}
@@ -0,0 +1,60 @@
+package rabbitmq
+
+import (
+	"context"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/sirupsen/logrus"
+	"github.com/www-xu/spark/log"
+)
+
+// logAdapter 把 watermill 的日志输出到 spark log
+type logAdapter struct {
+	fields watermill.LogFields
+}
+
+func newLogAdapter() watermill.LoggerAdapter {
+	return logAdapter{
+		fields: watermill.LogFields{"component": "rabbitmq"},
+	}
+}
+
+func (l logAdapter) entry(fields watermill.LogFields) *logrus.Entry {
+	merged := logrus.Fields{}
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+
+	return log.WithContext(context.Background()).WithFields(merged)
+}
+
+func (l logAdapter) Error(msg string, err error, fields watermill.LogFields) {
+	l.entry(fields).WithError(err).Error(msg)
+}
+
+func (l logAdapter) Info(msg string, fields watermill.LogFields) {
+	l.entry(fields).Info(msg)
+}
+
+func (l logAdapter) Debug(msg string, fields watermill.LogFields) {
+	l.entry(fields).Debug(msg)
+}
+
+func (l logAdapter) Trace(msg string, fields watermill.LogFields) {
+	l.entry(fields).Trace(msg)
+}
+
+func (l logAdapter) With(fields watermill.LogFields) watermill.LoggerAdapter {
+	merged := watermill.LogFields{}
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+
+	return logAdapter{fields: merged}
+}
@@ -0,0 +1,313 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/google/uuid"
+	"github.com/www-xu/spark/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultOutboxTable        = "rabbitmq_outbox"
+	defaultOutboxPollInterval = time.Second
+	defaultOutboxBatchSize    = 100
+	defaultOutboxMaxAttempts  = 10
+	defaultOutboxLeaseTimeout = time.Minute
+
+	OutboxStatusPending = "pending"
+	OutboxStatusDead    = "dead" // 投递失败次数达到 outbox.max_attempts，需要人工处理
+)
+
+// OutboxMessage 是 outbox 表中的一条待发布消息
+type OutboxMessage struct {
+	ID        uint64 `gorm:"primaryKey;autoIncrement"`
+	Exchange  string `gorm:"size:255;not null"`
+	Topic     string `gorm:"size:255;not null"`
+	UUID      string `gorm:"size:64;not null"`
+	Payload   []byte
+	Metadata  string `gorm:"type:text"`
+	Status    string `gorm:"size:16;not null;default:pending;index"`
+	Attempts  int    `gorm:"not null;default:0"`
+	LastError string `gorm:"type:text"`
+	// LockedBy 和 LockedUntil 标记正在发布该消息的 forwarder 及其租约
+	LockedBy    string `gorm:"size:64;not null;default:''"`
+	LockedUntil *time.Time
+	CreatedAt   time.Time
+}
+
+type outbox struct {
+	db      *gorm.DB
+	table   string
+	publish func(ctx context.Context, exchangeName, topic string, msg *message.Message, opts ...PublishOption) error
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// PublishTx 在调用方的 GORM 事务中把消息写入 outbox 表，事务提交后由 forwarder 投递到 RabbitMQ，至少投递一次。
+func PublishTx(ctx context.Context, tx *gorm.DB, exchangeName, topic string, msg *message.Message) error {
+	return instance.PublishTx(ctx, tx, exchangeName, topic, msg)
+}
+
+func (c *Component) PublishTx(ctx context.Context, tx *gorm.DB, exchangeName, topic string, msg *message.Message) error {
+	if _, ok := c.config.Exchanges[exchangeName]; !ok {
+		return errors.New("exchange isn't declared: " + exchangeName)
+	}
+
+	if msg.Metadata == nil {
+		msg.Metadata = message.Metadata{}
+	}
+	// 写入时记录 trace context，forwarder 发布时沿用
+	otel.GetTextMapPropagator().Inject(ctx, propagation.MapCarrier(msg.Metadata))
+
+	metadata, err := json.Marshal(msg.Metadata)
+	if err != nil {
+		return err
+	}
+
+	return tx.WithContext(ctx).Table(c.outboxTable()).Create(&OutboxMessage{
+		Exchange: exchangeName,
+		Topic:    topic,
+		UUID:     msg.UUID,
+		Payload:  msg.Payload,
+		Metadata: string(metadata),
+		Status:   OutboxStatusPending,
+	}).Error
+}
+
+// StartOutbox 创建 outbox 表并启动后台 forwarder，db 通常来自 mysql 或 postgres 组件，需要在 spark.Init 之后调用。
+func StartOutbox(db *gorm.DB) error {
+	return instance.StartOutbox(db)
+}
+
+func (c *Component) StartOutbox(db *gorm.DB) error {
+	if c.outbox != nil {
+		return errors.New("outbox is already started")
+	}
+
+	table := c.outboxTable()
+	err := db.Table(table).AutoMigrate(&OutboxMessage{})
+	if err != nil {
+		return err
+	}
+
+	interval := c.config.Outbox.PollInterval
+	if interval <= 0 {
+		interval = defaultOutboxPollInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.outbox = &outbox{
+		db:      db,
+		table:   table,
+		publish: c.Publish,
+		cancel:  cancel,
+	}
+
+	c.outbox.wg.Add(1)
+	go func() {
+		defer c.outbox.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for c.forwardOutbox(ctx) {
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// forwardOutbox 投递一批消息，返回是否可能还有待投递的消息。
+// 消息在一个短事务中领取并加上租约，发布期间不持有数据库事务和行锁，发布结果在第二个短事务中写回。
+func (c *Component) forwardOutbox(ctx context.Context) (more bool) {
+	batchSize := c.config.Outbox.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultOutboxBatchSize
+	}
+	maxAttempts := c.config.Outbox.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultOutboxMaxAttempts
+	}
+	lease := c.config.Outbox.LeaseTimeout
+	if lease <= 0 {
+		lease = defaultOutboxLeaseTimeout
+	}
+
+	claim, err := c.claimOutbox(ctx, batchSize, lease)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.WithContext(ctx).WithError(err).Error("failed to claim outbox messages")
+		}
+		return false
+	}
+
+	result := outboxResult{}
+	for _, row := range claim.rows {
+		// 租约到期后其他实例可能已经领取了剩余的消息
+		if ctx.Err() != nil || time.Now().After(claim.lockedUntil) {
+			break
+		}
+
+		err = c.forwardOutboxMessage(ctx, row)
+		if err == nil {
+			result.sent = append(result.sent, row.ID)
+			continue
+		}
+
+		if row.Attempts+1 >= maxAttempts {
+			// 重试耗尽的消息不再阻塞后续消息
+			log.WithContext(ctx).WithError(err).WithField("outbox_id", row.ID).Error("outbox message is dead after max attempts")
+			result.dead = append(result.dead, outboxFailure{id: row.ID, err: err})
+			continue
+		}
+
+		log.WithContext(ctx).WithError(err).WithField("outbox_id", row.ID).Warn("failed to forward outbox message")
+		// 保持顺序，失败后本批次剩余消息等待下次重试
+		result.retry = &outboxFailure{id: row.ID, err: err}
+		break
+	}
+
+	// 停止时也要写回已发布的消息，避免重复投递
+	err = c.completeOutbox(context.WithoutCancel(ctx), claim.token, result)
+	if err != nil {
+		log.WithContext(ctx).WithError(err).Error("failed to complete outbox messages")
+		return false
+	}
+
+	return len(claim.rows) == batchSize && result.retry == nil && len(result.sent)+len(result.dead) == len(claim.rows)
+}
+
+// outboxClaim 是一次领取的消息，token 和 lockedUntil 写在这些行上，租约到期前其他实例不会领取它们
+type outboxClaim struct {
+	rows        []OutboxMessage
+	token       string
+	lockedUntil time.Time
+}
+
+type outboxFailure struct {
+	id  uint64
+	err error
+}
+
+// outboxResult 是一批消息的发布结果，未出现在其中的已领取消息会被释放
+type outboxResult struct {
+	sent  []uint64
+	dead  []outboxFailure
+	retry *outboxFailure
+}
+
+func (c *Component) claimOutbox(ctx context.Context, batchSize int, lease time.Duration) (outboxClaim, error) {
+	now := time.Now()
+	claim := outboxClaim{token: uuid.NewString(), lockedUntil: now.Add(lease)}
+
+	err := c.outbox.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// SKIP LOCKED 允许多个实例同时领取，行锁只在这个事务内持有
+		err := tx.Table(c.outbox.table).
+			Clauses(clause.Locking{Strength: clause.LockingStrengthUpdate, Options: clause.LockingOptionsSkipLocked}).
+			Where("status = ? AND (locked_until IS NULL OR locked_until < ?)", OutboxStatusPending, now).
+			Order("id").
+			Limit(batchSize).
+			Find(&claim.rows).Error
+		if err != nil || len(claim.rows) == 0 {
+			return err
+		}
+
+		ids := make([]uint64, 0, len(claim.rows))
+		for _, row := range claim.rows {
+			ids = append(ids, row.ID)
+		}
+		return tx.Table(c.outbox.table).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"locked_by":    claim.token,
+			"locked_until": claim.lockedUntil,
+		}).Error
+	})
+
+	return claim, err
+}
+
+// completeOutbox 删除已发布的消息，记录失败并释放本次领取的其余消息。
+// 只修改仍由 token 持有的行，租约到期后被其他实例领取的消息由对方处理。
+func (c *Component) completeOutbox(ctx context.Context, token string, result outboxResult) error {
+	return c.outbox.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		owned := func() *gorm.DB {
+			return tx.Table(c.outbox.table).Where("locked_by = ?", token)
+		}
+
+		if len(result.sent) > 0 {
+			err := owned().Where("id IN ?", result.sent).Delete(&OutboxMessage{}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, dead := range result.dead {
+			err := owned().Where("id = ?", dead.id).Updates(map[string]interface{}{
+				"status":     OutboxStatusDead,
+				"attempts":   gorm.Expr("attempts + 1"),
+				"last_error": dead.err.Error(),
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if result.retry != nil {
+			err := owned().Where("id = ?", result.retry.id).Updates(map[string]interface{}{
+				"attempts":   gorm.Expr("attempts + 1"),
+				"last_error": result.retry.err.Error(),
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return owned().Updates(map[string]interface{}{
+			"locked_by":    "",
+			"locked_until": nil,
+		}).Error
+	})
+}
+
+func (c *Component) forwardOutboxMessage(ctx context.Context, row OutboxMessage) error {
+	msg := message.NewMessage(row.UUID, row.Payload)
+	if row.Metadata != "" {
+		err := json.Unmarshal([]byte(row.Metadata), &msg.Metadata)
+		if err != nil {
+			return err
+		}
+	}
+
+	return c.outbox.publish(ctx, row.Exchange, row.Topic, msg)
+}
+
+func (c *Component) stopOutbox() {
+	if c.outbox == nil {
+		return
+	}
+
+	c.outbox.cancel()
+	c.outbox.wg.Wait()
+}
+
+func (c *Component) outboxTable() string {
+	if c.config != nil && c.config.Outbox.Table != "" {
+		return c.config.Outbox.Table
+	}
+	return defaultOutboxTable
+}
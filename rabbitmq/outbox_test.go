@@ -0,0 +1,217 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestOutbox returns a component whose outbox forwards to publish, backed by an in-memory database
+// with a single connection, so a transaction held across publish would block publish's own queries.
+func newTestOutbox(t *testing.T, config OutboxConfig, publish func(ctx context.Context, exchangeName, topic string, msg *message.Message, opts ...PublishOption) error) (*Component, *gorm.DB) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	c := &Component{config: &Config{
+		Exchanges: map[string]ExchangeConfig{"orders": {Type: "topic"}},
+		Outbox:    config,
+	}}
+
+	err = db.Table(c.outboxTable()).AutoMigrate(&OutboxMessage{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.outbox = &outbox{db: db, table: c.outboxTable(), publish: publish}
+
+	return c, db
+}
+
+func addOutboxMessages(t *testing.T, c *Component, db *gorm.DB, uuids ...string) {
+	t.Helper()
+
+	for _, uuid := range uuids {
+		err := c.PublishTx(context.Background(), db, "orders", "created", message.NewMessage(uuid, []byte(`{"id":"`+uuid+`"}`)))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func outboxRows(t *testing.T, c *Component, db *gorm.DB) []OutboxMessage {
+	t.Helper()
+
+	var rows []OutboxMessage
+	err := db.Table(c.outboxTable()).Order("id").Find(&rows).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+func TestForwardOutboxPublishesOutsideTransaction(t *testing.T) {
+	var published []string
+	var c *Component
+	var db *gorm.DB
+	c, db = newTestOutbox(t, OutboxConfig{}, func(ctx context.Context, exchangeName, topic string, msg *message.Message, opts ...PublishOption) error {
+		if exchangeName != "orders" || topic != "created" || string(msg.Payload) != `{"id":"`+msg.UUID+`"}` {
+			t.Errorf("unexpected publish %s/%s %s", exchangeName, topic, msg.Payload)
+		}
+
+		// blocks on the only connection if the claim transaction were still open
+		queryCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		var count int64
+		err := db.WithContext(queryCtx).Table(c.outboxTable()).Count(&count).Error
+		if err != nil {
+			t.Errorf("publish runs inside a transaction: %v", err)
+		}
+
+		published = append(published, msg.UUID)
+		return nil
+	})
+
+	addOutboxMessages(t, c, db, "m1", "m2", "m3")
+
+	if c.forwardOutbox(context.Background()) {
+		t.Error("more = true after forwarding everything")
+	}
+	if len(published) != 3 || published[0] != "m1" || published[2] != "m3" {
+		t.Errorf("published %v, want m1 m2 m3 in order", published)
+	}
+	if rows := outboxRows(t, c, db); len(rows) != 0 {
+		t.Errorf("%d rows left after forwarding", len(rows))
+	}
+}
+
+func TestForwardOutboxStopsAtFailure(t *testing.T) {
+	var published []string
+	c, db := newTestOutbox(t, OutboxConfig{}, func(ctx context.Context, exchangeName, topic string, msg *message.Message, opts ...PublishOption) error {
+		if msg.UUID == "m2" {
+			return errors.New("broker unavailable")
+		}
+		published = append(published, msg.UUID)
+		return nil
+	})
+
+	addOutboxMessages(t, c, db, "m1", "m2", "m3")
+	c.forwardOutbox(context.Background())
+
+	if len(published) != 1 || published[0] != "m1" {
+		t.Errorf("published %v, want only m1", published)
+	}
+
+	rows := outboxRows(t, c, db)
+	if len(rows) != 2 {
+		t.Fatalf("%d rows left, want m2 and m3", len(rows))
+	}
+	if rows[0].UUID != "m2" || rows[0].Attempts != 1 || rows[0].LastError != "broker unavailable" {
+		t.Errorf("unexpected failed row %+v", rows[0])
+	}
+	for _, row := range rows {
+		if row.LockedBy != "" || row.LockedUntil != nil {
+			t.Errorf("row %s is still claimed", row.UUID)
+		}
+	}
+}
+
+func TestForwardOutboxMarksDeadAfterMaxAttempts(t *testing.T) {
+	var published []string
+	c, db := newTestOutbox(t, OutboxConfig{MaxAttempts: 2}, func(ctx context.Context, exchangeName, topic string, msg *message.Message, opts ...PublishOption) error {
+		if msg.UUID == "m1" {
+			return errors.New("rejected")
+		}
+		published = append(published, msg.UUID)
+		return nil
+	})
+
+	addOutboxMessages(t, c, db, "m1", "m2")
+	c.forwardOutbox(context.Background())
+	c.forwardOutbox(context.Background())
+
+	if len(published) != 1 || published[0] != "m2" {
+		t.Errorf("published %v, want m2 once m1 is dead", published)
+	}
+
+	rows := outboxRows(t, c, db)
+	if len(rows) != 1 || rows[0].UUID != "m1" || rows[0].Status != OutboxStatusDead || rows[0].Attempts != 2 {
+		t.Fatalf("unexpected rows %+v", rows)
+	}
+
+	// dead messages are never claimed again
+	c.forwardOutbox(context.Background())
+	if rows := outboxRows(t, c, db); rows[0].Attempts != 2 {
+		t.Errorf("dead message was retried, attempts = %d", rows[0].Attempts)
+	}
+}
+
+func TestForwardOutboxSkipsMessagesClaimedByAnotherForwarder(t *testing.T) {
+	var published []string
+	c, db := newTestOutbox(t, OutboxConfig{BatchSize: 3}, func(ctx context.Context, exchangeName, topic string, msg *message.Message, opts ...PublishOption) error {
+		published = append(published, msg.UUID)
+		return nil
+	})
+
+	addOutboxMessages(t, c, db, "m1", "m2", "m3")
+
+	claim, err := c.claimOutbox(context.Background(), 1, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(claim.rows) != 1 || claim.rows[0].UUID != "m1" {
+		t.Fatalf("unexpected claim %+v", claim.rows)
+	}
+
+	if c.forwardOutbox(context.Background()) {
+		t.Error("more = true after forwarding the last unclaimed messages")
+	}
+	if len(published) != 2 || published[0] != "m2" || published[1] != "m3" {
+		t.Errorf("published %v, want m2 m3", published)
+	}
+
+	// the other forwarder's write-back only touches the rows it still owns
+	err = c.completeOutbox(context.Background(), claim.token, outboxResult{sent: []uint64{claim.rows[0].ID}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows := outboxRows(t, c, db); len(rows) != 0 {
+		t.Errorf("%d rows left", len(rows))
+	}
+}
+
+func TestForwardOutboxReclaimsExpiredLease(t *testing.T) {
+	var published []string
+	c, db := newTestOutbox(t, OutboxConfig{}, func(ctx context.Context, exchangeName, topic string, msg *message.Message, opts ...PublishOption) error {
+		published = append(published, msg.UUID)
+		return nil
+	})
+
+	addOutboxMessages(t, c, db, "m1")
+
+	// a forwarder that crashed after claiming
+	_, err := c.claimOutbox(context.Background(), 1, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	c.forwardOutbox(context.Background())
+	if len(published) != 1 {
+		t.Errorf("published %v, want m1 after its lease expired", published)
+	}
+}
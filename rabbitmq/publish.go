@@ -0,0 +1,114 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+const defaultConfirmTimeout = 5 * time.Second
+
+type publishOptions struct {
+	delay time.Duration
+}
+
+type PublishOption func(*publishOptions)
+
+// WithDelay 延迟 d 后投递消息，不足 1ms 的部分会被忽略
+func WithDelay(d time.Duration) PublishOption {
+	return func(options *publishOptions) {
+		options.delay = d
+	}
+}
+
+// Publish 把消息发布到 exchange 的 topic 上，ctx 中的 trace context 会随消息传递。
+// 开启 publish.confirm 时会等待 broker 确认，超时返回错误并取消尚未发出的发布；已发出的消息仍可能被投递，
+// 因此超时后用相同 UUID 重试会等待上一次发布的结果，而不是再发布一次。
+// 使用 WithDelay 延迟投递，exchange 类型为 x-delayed-message 时依赖延迟插件，否则自动声明 TTL 延迟队列。
+func Publish(ctx context.Context, exchangeName, topic string, msg *message.Message, opts ...PublishOption) error {
+	return instance.Publish(ctx, exchangeName, topic, msg, opts...)
+}
+
+func (c *Component) Publish(ctx context.Context, exchangeName, topic string, msg *message.Message, opts ...PublishOption) error {
+	options := publishOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	publisher, err := c.GetPublisher(exchangeName)
+	if err != nil {
+		return err
+	}
+
+	if options.delay.Milliseconds() > 0 {
+		publisher, topic, err = c.delayTarget(exchangeName, topic, msg, options.delay)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !c.config.Publish.Confirm {
+		msg.SetContext(ctx)
+		return publisher.Publish(topic, msg)
+	}
+
+	return c.publishConfirmed(ctx, publisher, exchangeName, topic, msg)
+}
+
+// errPublishAbandoned 是确认超时或 ctx 取消后消息 context 的取消原因，tracingMarshaler 据此放弃尚未发出的发布
+var errPublishAbandoned = errors.New("publish abandoned before sending")
+
+func (c *Component) publishConfirmed(ctx context.Context, publisher message.Publisher, exchangeName, topic string, msg *message.Message) error {
+	timeout := c.config.Publish.ConfirmTimeout
+	if timeout <= 0 {
+		timeout = defaultConfirmTimeout
+	}
+
+	// 同一 UUID 的上一次发布仍未确认时等待它的结果，避免重试产生重复消息
+	pending := &pendingPublish{done: make(chan struct{})}
+	if existing, loaded := c.inflight.LoadOrStore(msg.UUID, pending); loaded {
+		return waitPublish(ctx, existing.(*pendingPublish), msg.UUID, exchangeName, topic, timeout)
+	}
+
+	// 发布可能阻塞在等待 channel 上，放弃后取消消息的 context，tracingMarshaler 在发送前检查并不再发出
+	publishCtx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	msg.SetContext(publishCtx)
+
+	// watermill 等待确认时不支持超时，放到单独的 goroutine 中等待
+	go func() {
+		defer cancel(nil)
+
+		pending.err = publisher.Publish(topic, msg)
+		c.inflight.Delete(msg.UUID)
+		close(pending.done)
+	}()
+
+	err := waitPublish(ctx, pending, msg.UUID, exchangeName, topic, timeout)
+	if err != nil {
+		cancel(errPublishAbandoned)
+	}
+	return err
+}
+
+// pendingPublish 是一次等待 broker 确认的发布，done 关闭后 err 为发布结果
+type pendingPublish struct {
+	done chan struct{}
+	err  error
+}
+
+func waitPublish(ctx context.Context, pending *pendingPublish, uuid, exchangeName, topic string, timeout time.Duration) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-pending.done:
+		return pending.err
+	case <-timer.C:
+		return fmt.Errorf("publish message %s to %s/%s: confirm timeout after %s", uuid, exchangeName, topic, timeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,140 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// blockingPublisher waits for release like a publish waiting for a free channel,
+// then marshals the message as watermill does right before sending it.
+type blockingPublisher struct {
+	release chan struct{}
+	calls   atomic.Int32
+	sent    atomic.Int32
+}
+
+func (p *blockingPublisher) Publish(topic string, messages ...*message.Message) error {
+	p.calls.Add(1)
+	<-p.release
+
+	for _, msg := range messages {
+		_, err := newMarshaler().Marshal(msg)
+		if err != nil {
+			return err
+		}
+		p.sent.Add(1)
+	}
+	return nil
+}
+
+func (p *blockingPublisher) Close() error { return nil }
+
+func newConfirmComponent(timeout time.Duration) *Component {
+	return &Component{config: &Config{Publish: PublishConfig{Confirm: true, ConfirmTimeout: timeout}}}
+}
+
+func waitUntil(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPublishConfirmed(t *testing.T) {
+	c := newConfirmComponent(time.Second)
+	publisher := &blockingPublisher{release: make(chan struct{})}
+	close(publisher.release)
+
+	err := c.publishConfirmed(context.Background(), publisher, "orders", "created", message.NewMessage("m1", []byte("{}")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if publisher.sent.Load() != 1 {
+		t.Errorf("sent %d messages, want 1", publisher.sent.Load())
+	}
+	if _, ok := c.inflight.Load("m1"); ok {
+		t.Error("confirmed publish is still in flight")
+	}
+}
+
+func TestPublishConfirmTimeoutAbandonsUnsentMessage(t *testing.T) {
+	c := newConfirmComponent(20 * time.Millisecond)
+	publisher := &blockingPublisher{release: make(chan struct{})}
+
+	err := c.publishConfirmed(context.Background(), publisher, "orders", "created", message.NewMessage("m1", []byte("{}")))
+	if err == nil {
+		t.Fatal("want a confirm timeout")
+	}
+
+	close(publisher.release)
+	waitUntil(t, func() bool {
+		_, ok := c.inflight.Load("m1")
+		return !ok
+	})
+	if publisher.sent.Load() != 0 {
+		t.Error("message abandoned after the confirm timeout was still sent")
+	}
+}
+
+func TestPublishCancelledContextAbandonsUnsentMessage(t *testing.T) {
+	c := newConfirmComponent(time.Second)
+	publisher := &blockingPublisher{release: make(chan struct{})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		waitUntil(t, func() bool { return publisher.calls.Load() == 1 })
+		cancel()
+	}()
+
+	err := c.publishConfirmed(ctx, publisher, "orders", "created", message.NewMessage("m1", []byte("{}")))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+
+	close(publisher.release)
+	waitUntil(t, func() bool {
+		_, ok := c.inflight.Load("m1")
+		return !ok
+	})
+	if publisher.sent.Load() != 0 {
+		t.Error("message abandoned after cancellation was still sent")
+	}
+}
+
+func TestPublishRetryJoinsInflightPublish(t *testing.T) {
+	c := newConfirmComponent(time.Second)
+	publisher := &blockingPublisher{release: make(chan struct{})}
+
+	// the first publish is sent but its confirm is slow, a retry with the same UUID must not send it again
+	first := message.NewMessage("m1", []byte("{}"))
+	c.inflight.Store(first.UUID, &pendingPublish{done: make(chan struct{})})
+	pending, _ := c.inflight.Load(first.UUID)
+
+	retry := message.NewMessage("m1", []byte("{}"))
+	result := make(chan error, 1)
+	go func() {
+		result <- c.publishConfirmed(context.Background(), publisher, "orders", "created", retry)
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	pending.(*pendingPublish).err = errors.New("nack")
+	close(pending.(*pendingPublish).done)
+
+	err := <-result
+	if err == nil || err.Error() != "nack" {
+		t.Fatalf("err = %v, want the result of the in-flight publish", err)
+	}
+	if publisher.calls.Load() != 0 {
+		t.Errorf("retry published %d times, want 0", publisher.calls.Load())
+	}
+}
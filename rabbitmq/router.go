@@ -0,0 +1,173 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/message/router/middleware"
+	"github.com/www-xu/spark/log"
+)
+
+const (
+	defaultConcurrency     = 1
+	defaultCloseTimeout    = 30 * time.Second
+	defaultMaxRetries      = 3
+	defaultInitialInterval = 100 * time.Millisecond
+	defaultMaxInterval     = 10 * time.Second
+	defaultMultiplier      = 2
+)
+
+type handlerRegistration struct {
+	exchange string
+	topic    string
+	handler  message.NoPublishHandlerFunc
+}
+
+// Handle 注册 exchange 上 topic 的消息处理函数，需要在 spark.Init 之前调用。
+// 组件初始化后会启动 router 消费消息，并在应用停止前等待处理中的消息完成。
+func Handle(exchangeName, topic string, handler message.NoPublishHandlerFunc) {
+	instance.Handle(exchangeName, topic, handler)
+}
+
+func (c *Component) Handle(exchangeName, topic string, handler message.NoPublishHandlerFunc) {
+	c.handlers = append(c.handlers, handlerRegistration{
+		exchange: exchangeName,
+		topic:    topic,
+		handler:  handler,
+	})
+}
+
+func (c *Component) startRouter() error {
+	if len(c.handlers) == 0 {
+		return nil
+	}
+
+	consumerConfig := c.config.Consumer
+	if consumerConfig.Concurrency <= 0 {
+		consumerConfig.Concurrency = defaultConcurrency
+	}
+	if consumerConfig.CloseTimeout <= 0 {
+		consumerConfig.CloseTimeout = defaultCloseTimeout
+	}
+
+	logger := c.logger
+
+	router, err := message.NewRouter(message.RouterConfig{
+		CloseTimeout: consumerConfig.CloseTimeout,
+	}, logger)
+	if err != nil {
+		return err
+	}
+
+	for _, registration := range c.handlers {
+		subscriber, err := c.GetSubscriber(registration.exchange)
+		if err != nil {
+			return err
+		}
+
+		middlewares, err := c.handlerMiddlewares(registration.exchange, registration.topic, consumerConfig, logger)
+		if err != nil {
+			return err
+		}
+		middlewares = append([]message.HandlerMiddleware{c.tracingMiddleware(registration.exchange, registration.topic)}, middlewares...)
+
+		// 同一个 queue 上的多个 consumer 竞争消费，以此实现并发
+		for i := 0; i < consumerConfig.Concurrency; i++ {
+			handler := router.AddNoPublisherHandler(
+				fmt.Sprintf("%s.%s.%d", registration.exchange, registration.topic, i),
+				registration.topic,
+				subscriber,
+				registration.handler,
+			)
+			handler.AddMiddleware(middlewares...)
+		}
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- router.Run(context.Background())
+	}()
+
+	select {
+	case <-router.Running():
+	case err = <-errs:
+		return err
+	}
+
+	c.router = router
+
+	return nil
+}
+
+// handlerMiddlewares 按从外到内的顺序返回：poison queue、重试、无效消息、panic 恢复，tracing 在最外层单独添加
+func (c *Component) handlerMiddlewares(exchangeName, topic string, config ConsumerConfig, logger watermill.LoggerAdapter) ([]message.HandlerMiddleware, error) {
+	var middlewares []message.HandlerMiddleware
+
+	if config.PoisonTopic != "" {
+		publisher, err := c.GetPublisher(exchangeName)
+		if err != nil {
+			return nil, err
+		}
+		poisonQueue, err := middleware.PoisonQueue(publisher, config.PoisonTopic)
+		if err != nil {
+			return nil, err
+		}
+		middlewares = append(middlewares, poisonQueue)
+	}
+
+	retry := middleware.Retry{
+		MaxRetries:      config.Retry.MaxRetries,
+		InitialInterval: config.Retry.InitialInterval,
+		MaxInterval:     config.Retry.MaxInterval,
+		Multiplier:      config.Retry.Multiplier,
+		Logger:          logger,
+	}
+	if retry.MaxRetries <= 0 {
+		retry.MaxRetries = defaultMaxRetries
+	}
+	if retry.InitialInterval <= 0 {
+		retry.InitialInterval = defaultInitialInterval
+	}
+	if retry.MaxInterval <= 0 {
+		retry.MaxInterval = defaultMaxInterval
+	}
+	if retry.Multiplier <= 0 {
+		retry.Multiplier = defaultMultiplier
+	}
+	middlewares = append(middlewares, retry.Middleware, c.invalidMessageMiddleware(exchangeName, topic, config.PoisonTopic), middleware.Recoverer)
+
+	return middlewares, nil
+}
+
+// invalidMessageMiddleware 跳过对 ErrInvalidMessage 的重试，直接发送到 poison topic，未配置 poison topic 时记录日志后丢弃
+func (c *Component) invalidMessageMiddleware(exchangeName, topic, poisonTopic string) message.HandlerMiddleware {
+	return func(h message.HandlerFunc) message.HandlerFunc {
+		return func(msg *message.Message) ([]*message.Message, error) {
+			messages, err := h(msg)
+			if !errors.Is(err, ErrInvalidMessage) {
+				return messages, err
+			}
+
+			log.WithContext(msg.Context()).WithError(err).WithField("message_uuid", msg.UUID).Warn("drop invalid rabbitmq message")
+			if poisonTopic == "" {
+				return nil, nil
+			}
+
+			msg.Metadata.Set(middleware.ReasonForPoisonedKey, err.Error())
+			msg.Metadata.Set(middleware.PoisonedTopicKey, topic)
+			return nil, c.Publish(msg.Context(), exchangeName, poisonTopic, msg)
+		}
+	}
+}
+
+func (c *Component) stopRouter() {
+	if c.router == nil {
+		return
+	}
+
+	_ = c.router.Close()
+}
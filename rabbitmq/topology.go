@@ -0,0 +1,134 @@
+package rabbitmq
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ThreeDotsLabs/watermill"
+	watermillAmqp "github.com/ThreeDotsLabs/watermill-amqp/v3/pkg/amqp"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// validateTopology 检查 queues 配置引用的 exchange 都已在 exchanges 中声明
+func (c *Component) validateTopology() error {
+	var errs []error
+
+	for queueName, queueConfig := range c.config.Queues {
+		if queueConfig.DeadLetterExchange != "" {
+			if _, ok := c.config.Exchanges[queueConfig.DeadLetterExchange]; !ok {
+				errs = append(errs, fmt.Errorf("queue %s: dead letter exchange %s isn't declared", queueName, queueConfig.DeadLetterExchange))
+			}
+		}
+
+		if queueConfig.Type == amqp.QueueTypeQuorum && (!queueConfig.Durable || queueConfig.AutoDelete || queueConfig.Exclusive) {
+			errs = append(errs, fmt.Errorf("queue %s: quorum queues must be durable, non auto-delete and non exclusive", queueName))
+		}
+
+		for _, binding := range queueConfig.Bindings {
+			if _, ok := c.config.Exchanges[binding.Exchange]; !ok {
+				errs = append(errs, fmt.Errorf("queue %s: binding exchange %s isn't declared", queueName, binding.Exchange))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// declareTopology 声明 exchanges、queues 及其绑定，重复声明相同参数是幂等的
+func (c *Component) declareTopology() error {
+	if len(c.config.Queues) == 0 {
+		return nil
+	}
+
+	err := c.validateTopology()
+	if err != nil {
+		return err
+	}
+
+	return c.withChannel(c.declareQueues)
+}
+
+func (c *Component) declareQueues(channel *amqp.Channel) error {
+	for exchangeName, exchangeConfig := range c.config.Exchanges {
+		err := channel.ExchangeDeclare(exchangeName, exchangeConfig.Type, exchangeConfig.Durable, exchangeConfig.AutoDeleted, false, false, exchangeConfig.Args)
+		if err != nil {
+			return fmt.Errorf("declare exchange %s: %w", exchangeName, err)
+		}
+	}
+
+	for queueName, queueConfig := range c.config.Queues {
+		_, err := channel.QueueDeclare(queueName, queueConfig.Durable, queueConfig.AutoDelete, queueConfig.Exclusive, false, queueArguments(queueConfig))
+		if err != nil {
+			return fmt.Errorf("declare queue %s: %w", queueName, err)
+		}
+
+		for _, binding := range queueConfig.Bindings {
+			routingKeys := binding.RoutingKeys
+			if len(routingKeys) == 0 {
+				routingKeys = []string{""}
+			}
+			for _, routingKey := range routingKeys {
+				err = channel.QueueBind(queueName, routingKey, binding.Exchange, false, binding.Args)
+				if err != nil {
+					return fmt.Errorf("bind queue %s to %s with %s: %w", queueName, binding.Exchange, routingKey, err)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// withChannel 在共享连接上打开一个临时 channel 执行声明操作，声明失败只会关闭这个 channel，不影响共享连接
+func (c *Component) withChannel(fn func(channel *amqp.Channel) error) error {
+	if !c.connection.IsConnected() {
+		return errors.New("rabbitmq connection is disconnected")
+	}
+
+	channel, err := c.connection.Connection().Channel()
+	if err != nil {
+		return err
+	}
+	defer channel.Close()
+
+	return fn(channel)
+}
+
+func queueArguments(config QueueConfig) amqp.Table {
+	args := amqp.Table{}
+	for k, v := range config.Args {
+		args[k] = v
+	}
+
+	if config.Type != "" {
+		args[amqp.QueueTypeArg] = config.Type
+	}
+	if config.MessageTTL > 0 {
+		args[amqp.QueueMessageTTLArg] = config.MessageTTL.Milliseconds()
+	}
+	if config.MaxLength > 0 {
+		args[amqp.QueueMaxLenArg] = config.MaxLength
+	}
+	if config.DeadLetterExchange != "" {
+		args["x-dead-letter-exchange"] = config.DeadLetterExchange
+	}
+	if config.DeadLetterRoutingKey != "" {
+		args["x-dead-letter-routing-key"] = config.DeadLetterRoutingKey
+	}
+
+	return args
+}
+
+// topologyBuilder 对于 queues 中配置的 queue 跳过 watermill 的默认声明，避免参数不一致导致 PRECONDITION_FAILED
+type topologyBuilder struct {
+	watermillAmqp.DefaultTopologyBuilder
+	queues map[string]QueueConfig
+}
+
+func (b *topologyBuilder) BuildTopology(channel *amqp.Channel, params watermillAmqp.BuildTopologyParams, config watermillAmqp.Config, logger watermill.LoggerAdapter) error {
+	if _, ok := b.queues[params.QueueName]; ok {
+		return nil
+	}
+
+	return b.DefaultTopologyBuilder.BuildTopology(channel, params, config, logger)
+}
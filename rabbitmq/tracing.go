@@ -0,0 +1,93 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	watermillAmqp "github.com/ThreeDotsLabs/watermill-amqp/v3/pkg/amqp"
+	"github.com/ThreeDotsLabs/watermill/message"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/www-xu/spark/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("rabbitmq")
+
+// tracingMarshaler 在发布时把 message context 中的 trace context 写入 AMQP headers，
+// 调用方需要通过 msg.SetContext(ctx) 传入请求的 context。
+// watermill 在取得 channel 之后、发送之前调用 Marshal，而 amqp091 忽略发布的 ctx，
+// 所以已放弃的发布在这里拒绝，不会再发出。
+type tracingMarshaler struct {
+	watermillAmqp.DefaultMarshaler
+}
+
+func (m tracingMarshaler) Marshal(msg *message.Message) (amqp.Publishing, error) {
+	if errors.Is(context.Cause(msg.Context()), errPublishAbandoned) {
+		return amqp.Publishing{}, errPublishAbandoned
+	}
+
+	if msg.Metadata == nil {
+		msg.Metadata = message.Metadata{}
+	}
+	otel.GetTextMapPropagator().Inject(msg.Context(), propagation.MapCarrier(msg.Metadata))
+
+	publishing, err := m.DefaultMarshaler.Marshal(msg)
+	if err != nil {
+		return publishing, err
+	}
+
+	// 延迟插件只识别整数类型的 x-delay
+	if value, ok := publishing.Headers[delayHeader].(string); ok {
+		delay, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return publishing, fmt.Errorf("invalid %s header %q: %w", delayHeader, value, err)
+		}
+		publishing.Headers[delayHeader] = delay
+	}
+
+	return publishing, nil
+}
+
+// tracingMiddleware 从 AMQP headers 中恢复 trace context，并为每条消息创建 consumer span，
+// 同时把 trace id 写入 message context 以便 handler 中的日志关联。
+func (c *Component) tracingMiddleware(exchangeName, topic string) message.HandlerMiddleware {
+	routingKey := c.routingKey(topic)
+
+	return func(h message.HandlerFunc) message.HandlerFunc {
+		return func(msg *message.Message) ([]*message.Message, error) {
+			ctx := otel.GetTextMapPropagator().Extract(msg.Context(), propagation.MapCarrier(msg.Metadata))
+
+			ctx, span := tracer.Start(ctx, topic+" process",
+				trace.WithSpanKind(trace.SpanKindConsumer),
+				trace.WithAttributes(
+					semconv.MessagingSystemRabbitMQ,
+					semconv.MessagingOperationTypeProcess,
+					semconv.MessagingDestinationName(exchangeName),
+					semconv.MessagingRabbitMQDestinationRoutingKey(routingKey),
+					semconv.MessagingMessageID(msg.UUID),
+					semconv.MessagingConsumerGroupName(topic),
+				),
+			)
+			defer span.End()
+
+			spanCtx := trace.SpanContextFromContext(ctx)
+			ctx = context.WithValue(ctx, log.TraceIdKey, spanCtx.TraceID().String())
+			ctx = context.WithValue(ctx, log.SpanIdKey, spanCtx.SpanID().String())
+			msg.SetContext(ctx)
+
+			messages, err := h(msg)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+
+			return messages, err
+		}
+	}
+}
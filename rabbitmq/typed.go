@@ -0,0 +1,127 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+const (
+	ContentTypeHeader   = "content_type"
+	SchemaVersionHeader = "schema_version"
+)
+
+// ErrInvalidMessage 表示消息无法解码或校验失败，重试没有意义，会直接发送到 poison topic
+var ErrInvalidMessage = errors.New("invalid message")
+
+// Validator 由消息类型实现，消费时解码后调用，返回错误的消息视为无效消息
+type Validator interface {
+	Validate() error
+}
+
+type typedOptions struct {
+	codec         Codec
+	schemaVersion string
+}
+
+type TypedOption func(*typedOptions)
+
+// WithCodec 指定编码使用的 codec，默认 JSONCodec。消费时优先使用消息 content_type 对应的 codec
+func WithCodec(codec Codec) TypedOption {
+	return func(options *typedOptions) {
+		options.codec = codec
+	}
+}
+
+// WithSchemaVersion 发布时写入 schema_version header，消费时拒绝 schema_version 不同的消息
+func WithSchemaVersion(version string) TypedOption {
+	return func(options *typedOptions) {
+		options.schemaVersion = version
+	}
+}
+
+func newTypedOptions(opts []TypedOption) typedOptions {
+	options := typedOptions{codec: JSONCodec{}}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// Publisher 把 T 编码后发布到固定的 exchange 和 topic
+type Publisher[T any] struct {
+	exchange string
+	topic    string
+	options  typedOptions
+}
+
+func NewPublisher[T any](exchangeName, topic string, opts ...TypedOption) *Publisher[T] {
+	return &Publisher[T]{
+		exchange: exchangeName,
+		topic:    topic,
+		options:  newTypedOptions(opts),
+	}
+}
+
+func (p *Publisher[T]) Publish(ctx context.Context, value T, opts ...PublishOption) error {
+	payload, err := p.options.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	msg := message.NewMessage(watermill.NewUUID(), payload)
+	msg.Metadata.Set(ContentTypeHeader, p.options.codec.ContentType())
+	if p.options.schemaVersion != "" {
+		msg.Metadata.Set(SchemaVersionHeader, p.options.schemaVersion)
+	}
+
+	return Publish(ctx, p.exchange, p.topic, msg, opts...)
+}
+
+// Handler 把 fn 包装为 Handle 使用的处理函数，例如 rabbitmq.Handle("orders", "order.created", rabbitmq.Handler(fn))。
+// 解码或校验失败返回 ErrInvalidMessage，不会重试。
+func Handler[T any](fn func(ctx context.Context, value T) error, opts ...TypedOption) message.NoPublishHandlerFunc {
+	options := newTypedOptions(opts)
+
+	return func(msg *message.Message) error {
+		value, err := decode[T](msg, options)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+		}
+
+		return fn(msg.Context(), value)
+	}
+}
+
+func decode[T any](msg *message.Message, options typedOptions) (T, error) {
+	var value T
+
+	if version := msg.Metadata.Get(SchemaVersionHeader); options.schemaVersion != "" && version != "" && version != options.schemaVersion {
+		return value, fmt.Errorf("unexpected schema version %s, want %s", version, options.schemaVersion)
+	}
+
+	codec := options.codec
+	if contentType := msg.Metadata.Get(ContentTypeHeader); contentType != "" {
+		var ok bool
+		codec, ok = codecFor(contentType)
+		if !ok {
+			return value, fmt.Errorf("unsupported content type %s", contentType)
+		}
+	}
+
+	err := codec.Unmarshal(msg.Payload, &value)
+	if err != nil {
+		return value, err
+	}
+
+	if validator, ok := any(value).(Validator); ok {
+		err = validator.Validate()
+	} else if validator, ok := any(&value).(Validator); ok {
+		err = validator.Validate()
+	}
+
+	return value, err
+}
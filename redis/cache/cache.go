@@ -0,0 +1,358 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	mrand "math/rand"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"github.com/www-xu/spark"
+	"github.com/www-xu/spark/log"
+	sparkredis "github.com/www-xu/spark/redis"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrNotFound is returned when a key is absent from the cache. Loaders may also
+// return it to signal that the value doesn't exist, which is then negatively cached.
+var ErrNotFound = errors.New("cache: not found")
+
+const (
+	defaultTTL      = 10 * time.Minute
+	defaultLocalTTL = time.Minute
+
+	// every value stored in redis is prefixed by one marker byte so that
+	// negative entries can't collide with any codec output.
+	markerValue    byte = 'v'
+	markerNegative byte = 'n'
+)
+
+// Options configures a Cache.
+type Options struct {
+	// TTL is the expiry of entries in redis.
+	TTL time.Duration
+	// Jitter randomly extends TTL by up to this fraction (e.g. 0.1 for 10%) to spread expirations.
+	Jitter float64
+	// NegativeTTL enables negative caching of ErrNotFound results from loaders when positive.
+	NegativeTTL time.Duration
+	// Codec defaults to JSONCodec.
+	Codec Codec
+	// LocalSize enables an in-process LRU tier holding up to LocalSize entries when positive.
+	LocalSize int
+	// LocalTTL is the expiry of in-process entries.
+	LocalTTL time.Duration
+}
+
+type localEntry[T any] struct {
+	value    T
+	negative bool
+}
+
+// Cache is a typed redis cache with optional in-process tier.
+type Cache[T any] struct {
+	name    string
+	options Options
+	group   singleflight.Group
+
+	local      *expirable.LRU[string, localEntry[T]]
+	instanceID string
+	pubsub     *redis.PubSub
+
+	hits   metric.Int64Counter
+	misses metric.Int64Counter
+}
+
+// New creates a cache whose keys are namespaced by name.
+// The redis client is resolved lazily through the redis component, so caches may be declared before spark.Init.
+func New[T any](name string, options *Options) *Cache[T] {
+	c := &Cache[T]{
+		name: name,
+	}
+	if options != nil {
+		c.options = *options
+	}
+	if c.options.TTL <= 0 {
+		c.options.TTL = defaultTTL
+	}
+	if c.options.Codec == nil {
+		c.options.Codec = JSONCodec{}
+	}
+	if c.options.LocalSize > 0 {
+		if c.options.LocalTTL <= 0 {
+			c.options.LocalTTL = defaultLocalTTL
+		}
+		c.local = expirable.NewLRU[string, localEntry[T]](c.options.LocalSize, nil, c.options.LocalTTL)
+		c.instanceID = newInstanceID()
+
+		// listen before any entry is cached locally, caches declared before spark.Init
+		// listen once the redis component is initialized
+		if sparkredis.Get(context.Background()) != nil {
+			c.listen()
+		} else {
+			listener := &invalidationListener[T]{cache: c}
+			spark.RegisterApplicationInitEventListener(listener)
+			spark.RegisterApplicationStopEventListener(listener)
+		}
+	}
+
+	meter := otel.Meter("cache")
+	c.hits, _ = meter.Int64Counter("cache.hits", metric.WithDescription("number of cache hits"))
+	c.misses, _ = meter.Int64Counter("cache.misses", metric.WithDescription("number of cache misses"))
+
+	return c
+}
+
+// Get returns the cached value for key, or ErrNotFound.
+func (c *Cache[T]) Get(ctx context.Context, key string) (value T, err error) {
+	entry, found, err := c.lookup(ctx, key)
+	if err != nil {
+		return value, err
+	}
+	if !found || entry.negative {
+		return value, ErrNotFound
+	}
+
+	return entry.value, nil
+}
+
+func (c *Cache[T]) lookup(ctx context.Context, key string) (entry localEntry[T], found bool, err error) {
+	if entry, ok := c.getLocal(ctx, key); ok {
+		c.record(ctx, c.hits, "local")
+		return entry, true, nil
+	}
+	if c.local != nil {
+		c.record(ctx, c.misses, "local")
+	}
+
+	data, err := sparkredis.Get(ctx).Get(ctx, c.redisKey(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		c.record(ctx, c.misses, "redis")
+		return entry, false, nil
+	}
+	if err != nil {
+		return entry, false, err
+	}
+	c.record(ctx, c.hits, "redis")
+
+	entry, err = c.decode(data)
+	if err != nil {
+		return entry, false, err
+	}
+	c.setLocal(key, entry)
+
+	return entry, true, nil
+}
+
+// Set stores value under key with the configured TTL.
+func (c *Cache[T]) Set(ctx context.Context, key string, value T) error {
+	data, err := c.options.Codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	err = sparkredis.Get(ctx).Set(ctx, c.redisKey(key), append([]byte{markerValue}, data...), c.ttl(c.options.TTL)).Err()
+	if err != nil {
+		return err
+	}
+	c.setLocal(key, localEntry[T]{value: value})
+	c.invalidate(ctx, key)
+
+	return nil
+}
+
+// Delete removes key from redis and from every in-process tier.
+func (c *Cache[T]) Delete(ctx context.Context, key string) error {
+	err := sparkredis.Get(ctx).Del(ctx, c.redisKey(key)).Err()
+	if err != nil {
+		return err
+	}
+	if c.local != nil {
+		c.local.Remove(key)
+	}
+	c.invalidate(ctx, key)
+
+	return nil
+}
+
+// GetOrLoad returns the cached value for key, calling loader on a miss and caching its result.
+// Concurrent misses on the same key share a single loader call.
+func (c *Cache[T]) GetOrLoad(ctx context.Context, key string, loader func(ctx context.Context) (T, error)) (T, error) {
+	entry, found, err := c.lookup(ctx, key)
+	if err == nil && found {
+		if entry.negative {
+			return entry.value, ErrNotFound
+		}
+		return entry.value, nil
+	}
+	if err != nil {
+		log.WithContext(ctx).WithError(err).WithField("cache", c.name).Warn("failed to read cache, falling back to loader")
+	}
+
+	// the shared loader must not fail every waiting caller when the first caller gives up,
+	// so it runs detached from the caller's cancellation while each caller still honors its own ctx
+	loadCtx := context.WithoutCancel(ctx)
+	resultCh := c.group.DoChan(key, func() (interface{}, error) {
+		loaded, err := loader(loadCtx)
+		if errors.Is(err, ErrNotFound) {
+			if c.options.NegativeTTL > 0 {
+				c.setNegative(loadCtx, key)
+			}
+			return loaded, err
+		}
+		if err != nil {
+			return loaded, err
+		}
+
+		if setErr := c.Set(loadCtx, key, loaded); setErr != nil {
+			log.WithContext(loadCtx).WithError(setErr).WithField("cache", c.name).Warn("failed to write cache")
+		}
+		return loaded, nil
+	})
+
+	var zero T
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case result := <-resultCh:
+		if result.Err != nil {
+			return zero, result.Err
+		}
+		value, _ := result.Val.(T)
+		return value, nil
+	}
+}
+
+// Close stops listening for invalidation broadcasts.
+func (c *Cache[T]) Close() error {
+	if c.pubsub != nil {
+		return c.pubsub.Close()
+	}
+	return nil
+}
+
+func (c *Cache[T]) setNegative(ctx context.Context, key string) {
+	err := sparkredis.Get(ctx).Set(ctx, c.redisKey(key), []byte{markerNegative}, c.ttl(c.options.NegativeTTL)).Err()
+	if err != nil {
+		log.WithContext(ctx).WithError(err).WithField("cache", c.name).Warn("failed to write negative cache entry")
+		return
+	}
+	c.setLocal(key, localEntry[T]{negative: true})
+	c.invalidate(ctx, key)
+}
+
+func (c *Cache[T]) decode(data []byte) (entry localEntry[T], err error) {
+	if len(data) == 0 {
+		return entry, fmt.Errorf("cache: empty entry in %s", c.name)
+	}
+
+	switch data[0] {
+	case markerNegative:
+		entry.negative = true
+		return entry, nil
+	case markerValue:
+		err = c.options.Codec.Unmarshal(data[1:], &entry.value)
+		return entry, err
+	default:
+		return entry, fmt.Errorf("cache: unknown entry marker %q in %s", data[0], c.name)
+	}
+}
+
+func (c *Cache[T]) getLocal(ctx context.Context, key string) (entry localEntry[T], ok bool) {
+	if c.local == nil {
+		return entry, false
+	}
+
+	return c.local.Get(key)
+}
+
+func (c *Cache[T]) setLocal(key string, entry localEntry[T]) {
+	if c.local == nil {
+		return
+	}
+	c.local.Add(key, entry)
+}
+
+// invalidate tells the other replicas to drop key from their in-process tier.
+func (c *Cache[T]) invalidate(ctx context.Context, key string) {
+	if c.local == nil {
+		return
+	}
+
+	err := sparkredis.Get(ctx).Publish(ctx, c.channel(), c.instanceID+"|"+key).Err()
+	if err != nil {
+		log.WithContext(ctx).WithError(err).WithField("cache", c.name).Warn("failed to broadcast cache invalidation")
+	}
+}
+
+func (c *Cache[T]) listen() {
+	c.pubsub = sparkredis.Get(context.Background()).Subscribe(context.Background(), c.channel())
+
+	go func() {
+		for msg := range c.pubsub.Channel() {
+			origin, key, ok := strings.Cut(msg.Payload, "|")
+			if !ok || origin == c.instanceID {
+				continue
+			}
+			c.local.Remove(key)
+		}
+	}()
+}
+
+// invalidationListener subscribes a cache declared before spark.Init once redis is initialized,
+// and unsubscribes it before redis is closed.
+type invalidationListener[T any] struct {
+	cache *Cache[T]
+}
+
+func (l *invalidationListener[T]) BeforeInit() error {
+	return nil
+}
+
+func (l *invalidationListener[T]) AfterInit(applicationContext *spark.ApplicationContext) error {
+	l.cache.listen()
+	return nil
+}
+
+func (l *invalidationListener[T]) BeforeStop() {
+	_ = l.cache.Close()
+}
+
+func (l *invalidationListener[T]) AfterStop() {
+	return
+}
+
+func (c *Cache[T]) record(ctx context.Context, counter metric.Int64Counter, tier string) {
+	counter.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("cache.name", c.name),
+		attribute.String("cache.tier", tier),
+	))
+}
+
+func (c *Cache[T]) ttl(base time.Duration) time.Duration {
+	if c.options.Jitter <= 0 {
+		return base
+	}
+	return base + time.Duration(mrand.Float64()*c.options.Jitter*float64(base))
+}
+
+func (c *Cache[T]) redisKey(key string) string {
+	return fmt.Sprintf("cache:%s:%s", c.name, key)
+}
+
+func (c *Cache[T]) channel() string {
+	return fmt.Sprintf("cache:%s:invalidate", c.name)
+}
+
+func newInstanceID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
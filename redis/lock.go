@@ -0,0 +1,158 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redsync/redsync/v4"
+	"github.com/www-xu/spark/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+var tracer = otel.Tracer("redis")
+
+var (
+	// ErrLockNotAcquired is returned by WithLock when the lock could not be obtained within the wait timeout.
+	ErrLockNotAcquired = errors.New("redis lock not acquired")
+	// ErrLockLost is the cancellation cause of the function context when the lease could not be extended.
+	ErrLockLost = errors.New("redis lock lost")
+)
+
+const (
+	defaultLockExpiry     = 8 * time.Second
+	defaultLockTries      = 32
+	defaultLockRetryDelay = 100 * time.Millisecond
+)
+
+// LockOptions controls how WithLock acquires and keeps a lock.
+// Zero values fall back to sensible defaults.
+type LockOptions struct {
+	// Expiry is the lease duration of the lock in redis.
+	Expiry time.Duration
+	// Tries is the maximum number of acquire attempts.
+	Tries int
+	// RetryDelay is the delay between acquire attempts.
+	RetryDelay time.Duration
+	// WaitTimeout bounds the total time spent acquiring the lock, 0 means no bound besides Tries.
+	WaitTimeout time.Duration
+	// ExtendInterval is how often the lease is extended while the function runs, defaults to Expiry/3.
+	ExtendInterval time.Duration
+}
+
+func (o *LockOptions) withDefaults() LockOptions {
+	opts := LockOptions{}
+	if o != nil {
+		opts = *o
+	}
+	if opts.Expiry <= 0 {
+		opts.Expiry = defaultLockExpiry
+	}
+	if opts.Tries <= 0 {
+		opts.Tries = defaultLockTries
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = defaultLockRetryDelay
+	}
+	if opts.ExtendInterval <= 0 || opts.ExtendInterval >= opts.Expiry {
+		opts.ExtendInterval = opts.Expiry / 3
+	}
+	return opts
+}
+
+// WithLock runs fn while holding the distributed lock identified by name.
+// The lease is extended in the background until fn returns; if an extension fails,
+// the context passed to fn is cancelled with ErrLockLost as its cause.
+// The lock is always released before WithLock returns, after the background extension has stopped.
+func WithLock(ctx context.Context, name string, opts *LockOptions, fn func(ctx context.Context) error) error {
+	return instance.WithLock(ctx, name, opts, fn)
+}
+
+func (c *Component) WithLock(ctx context.Context, name string, opts *LockOptions, fn func(ctx context.Context) error) (err error) {
+	o := opts.withDefaults()
+
+	mutex := c.Locker(ctx).NewMutex(name,
+		redsync.WithExpiry(o.Expiry),
+		redsync.WithTries(o.Tries),
+		redsync.WithRetryDelay(o.RetryDelay),
+	)
+
+	err = c.acquire(ctx, mutex, o)
+	if err != nil {
+		return err
+	}
+
+	lockCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		c.keepAlive(lockCtx, mutex, o, cancel, done)
+	}()
+
+	defer func() {
+		// an extension still in flight must not race with the release below
+		close(done)
+		<-stopped
+
+		releaseCtx, releaseCancel := context.WithTimeout(context.WithoutCancel(ctx), o.Expiry)
+		defer releaseCancel()
+
+		ok, unlockErr := mutex.UnlockContext(releaseCtx)
+		if unlockErr != nil || !ok {
+			log.WithContext(ctx).WithError(unlockErr).WithField("lock", name).Warn("failed to release redis lock")
+		}
+	}()
+
+	return fn(lockCtx)
+}
+
+func (c *Component) acquire(ctx context.Context, mutex *redsync.Mutex, opts LockOptions) error {
+	ctx, span := tracer.Start(ctx, "redis.lock.acquire")
+	defer span.End()
+
+	if opts.WaitTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.WaitTimeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := mutex.LockContext(ctx)
+	span.SetAttributes(
+		attribute.String("lock.name", mutex.Name()),
+		attribute.Int64("lock.wait_ms", time.Since(start).Milliseconds()),
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return errors.Join(ErrLockNotAcquired, err)
+	}
+
+	return nil
+}
+
+func (c *Component) keepAlive(ctx context.Context, mutex *redsync.Mutex, opts LockOptions, cancel context.CancelCauseFunc, done <-chan struct{}) {
+	ticker := time.NewTicker(opts.ExtendInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ok, err := mutex.ExtendContext(ctx)
+			if err != nil || !ok {
+				log.WithContext(ctx).WithError(err).WithField("lock", mutex.Name()).Error("failed to extend redis lock")
+				cancel(ErrLockLost)
+				return
+			}
+		}
+	}
+}
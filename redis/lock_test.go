@@ -0,0 +1,79 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redsync/redsync/v4"
+	"github.com/go-redsync/redsync/v4/redis/goredis/v8"
+)
+
+func newTestComponent(t *testing.T) (*Component, *miniredis.Miniredis) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return &Component{instance: client, locker: redsync.New(goredis.NewPool(client))}, server
+}
+
+func TestWithLockExtendsAndReleases(t *testing.T) {
+	c, server := newTestComponent(t)
+	opts := &LockOptions{Expiry: time.Second, ExtendInterval: 5 * time.Millisecond}
+
+	for i := 0; i < 20; i++ {
+		err := c.WithLock(context.Background(), "job", opts, func(ctx context.Context) error {
+			time.Sleep(20 * time.Millisecond)
+			if !server.Exists("job") {
+				t.Error("lock isn't held while fn runs")
+			}
+			return ctx.Err()
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if server.Exists("job") {
+			t.Fatal("lock is still held after WithLock returned")
+		}
+	}
+}
+
+func TestWithLockCancelsWhenLeaseIsLost(t *testing.T) {
+	c, server := newTestComponent(t)
+	opts := &LockOptions{Expiry: time.Second, ExtendInterval: 5 * time.Millisecond}
+
+	err := c.WithLock(context.Background(), "job", opts, func(ctx context.Context) error {
+		server.Del("job")
+
+		select {
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-time.After(time.Second):
+			return nil
+		}
+	})
+	if !errors.Is(err, ErrLockLost) {
+		t.Fatalf("err = %v, want ErrLockLost", err)
+	}
+}
+
+func TestWithLockNotAcquired(t *testing.T) {
+	c, _ := newTestComponent(t)
+	opts := &LockOptions{Expiry: time.Second, Tries: 2, RetryDelay: time.Millisecond}
+
+	err := c.WithLock(context.Background(), "job", opts, func(ctx context.Context) error {
+		return c.WithLock(ctx, "job", opts, func(ctx context.Context) error {
+			t.Error("lock acquired twice")
+			return nil
+		})
+	})
+	if !errors.Is(err, ErrLockNotAcquired) {
+		t.Fatalf("err = %v, want ErrLockNotAcquired", err)
+	}
+}
@@ -0,0 +1,73 @@
+package stream
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+	sparkredis "github.com/www-xu/spark/redis"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("redis-stream")
+
+// Message is an entry read from a stream.
+type Message struct {
+	ID     string
+	Stream string
+	Values map[string]interface{}
+}
+
+type Producer struct {
+	maxLen int64
+}
+
+// NewProducer creates a producer, streams are approximately trimmed to maxLen entries when it is positive.
+func NewProducer(maxLen int64) *Producer {
+	return &Producer{
+		maxLen: maxLen,
+	}
+}
+
+// Publish appends values to stream without trimming.
+func Publish(ctx context.Context, stream string, values map[string]interface{}) (string, error) {
+	return NewProducer(0).Publish(ctx, stream, values)
+}
+
+// Publish appends values to stream, adding the trace context of ctx as extra fields.
+func (p *Producer) Publish(ctx context.Context, stream string, values map[string]interface{}) (id string, err error) {
+	ctx, span := tracer.Start(ctx, stream+" publish", trace.WithSpanKind(trace.SpanKindProducer))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.SetAttributes(attribute.String("messaging.message.id", id))
+		span.End()
+	}()
+	span.SetAttributes(
+		attribute.String("messaging.system", "redis"),
+		attribute.String("messaging.destination.name", stream),
+	)
+
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+
+	fields := make(map[string]interface{}, len(values)+len(carrier))
+	for k, v := range values {
+		fields[k] = v
+	}
+	for k, v := range carrier {
+		fields[k] = v
+	}
+
+	return sparkredis.Get(ctx).XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: p.maxLen,
+		Approx: p.maxLen > 0,
+		Values: fields,
+	}).Result()
+}
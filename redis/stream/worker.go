@@ -0,0 +1,365 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/www-xu/spark"
+	"github.com/www-xu/spark/log"
+	sparkredis "github.com/www-xu/spark/redis"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultConcurrency   = 1
+	defaultBlockTimeout  = 2 * time.Second
+	defaultClaimMinIdle  = time.Minute
+	defaultClaimInterval = 30 * time.Second
+	defaultMaxDeliveries = 5
+	claimBatchSize       = 100
+)
+
+// Handler processes one message, the message is acknowledged only when it returns nil.
+type Handler func(ctx context.Context, msg *Message) error
+
+type WorkerConfig struct {
+	Stream   string `mapstructure:"stream"`
+	Group    string `mapstructure:"group"`
+	Consumer string `mapstructure:"consumer"` // defaults to hostname-pid
+	// StartID is where a newly created group starts reading, "$" (default) for new entries only, "0" for the whole stream.
+	StartID     string `mapstructure:"start_id"`
+	Concurrency int    `mapstructure:"concurrency"`
+	// BlockTimeout bounds each XREADGROUP call and therefore how long Stop waits for the reader.
+	BlockTimeout time.Duration `mapstructure:"block_timeout"`
+	// ClaimMinIdle is how long an entry stays pending before it is reclaimed from a crashed consumer.
+	ClaimMinIdle  time.Duration `mapstructure:"claim_min_idle"`
+	ClaimInterval time.Duration `mapstructure:"claim_interval"`
+	// MaxDeliveries moves entries delivered this many times to DeadLetterStream.
+	MaxDeliveries    int64  `mapstructure:"max_deliveries"`
+	DeadLetterStream string `mapstructure:"dead_letter_stream"` // defaults to <stream>:dead
+}
+
+// Worker consumes a stream as a member of a consumer group.
+type Worker struct {
+	config  WorkerConfig
+	handler Handler
+	client  func(ctx context.Context) *redis.Client
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewWorker creates a worker, it is stopped gracefully before the application stops.
+func NewWorker(config WorkerConfig, handler Handler) *Worker {
+	if config.Consumer == "" {
+		hostname, _ := os.Hostname()
+		config.Consumer = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+	if config.StartID == "" {
+		config.StartID = "$"
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = defaultConcurrency
+	}
+	if config.BlockTimeout <= 0 {
+		config.BlockTimeout = defaultBlockTimeout
+	}
+	if config.ClaimMinIdle <= 0 {
+		config.ClaimMinIdle = defaultClaimMinIdle
+	}
+	if config.ClaimInterval <= 0 {
+		config.ClaimInterval = defaultClaimInterval
+	}
+	if config.MaxDeliveries <= 0 {
+		config.MaxDeliveries = defaultMaxDeliveries
+	}
+	if config.DeadLetterStream == "" {
+		config.DeadLetterStream = config.Stream + ":dead"
+	}
+
+	w := &Worker{
+		config:  config,
+		handler: handler,
+		client:  sparkredis.Get,
+	}
+	spark.RegisterApplicationStopEventListener(w)
+
+	return w
+}
+
+// Start creates the consumer group if needed and starts consuming in the background.
+func (w *Worker) Start(ctx context.Context) error {
+	err := w.client(ctx).XGroupCreateMkStream(ctx, w.config.Stream, w.config.Group, w.config.StartID).Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+
+	// handlers keep running on a context that outlives Stop so in-flight messages can be acknowledged
+	handlerCtx := context.WithoutCancel(ctx)
+	ctx, w.cancel = context.WithCancel(ctx)
+
+	messages := make(chan *Message, w.config.Concurrency)
+
+	var producers sync.WaitGroup
+	producers.Add(2)
+	go func() {
+		defer producers.Done()
+		w.read(ctx, messages)
+	}()
+	go func() {
+		defer producers.Done()
+		w.claim(ctx, messages)
+	}()
+	go func() {
+		producers.Wait()
+		close(messages)
+	}()
+
+	for i := 0; i < w.config.Concurrency; i++ {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			for msg := range messages {
+				w.process(handlerCtx, msg)
+			}
+		}()
+	}
+
+	return nil
+}
+
+// Stop stops reading and waits for in-flight messages.
+func (w *Worker) Stop() {
+	if w.cancel == nil {
+		return
+	}
+	w.cancel()
+	w.wg.Wait()
+}
+
+func (w *Worker) BeforeStop() {
+	w.Stop()
+}
+
+func (w *Worker) AfterStop() {}
+
+func (w *Worker) read(ctx context.Context, messages chan<- *Message) {
+	for ctx.Err() == nil {
+		streams, err := w.client(ctx).XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    w.config.Group,
+			Consumer: w.config.Consumer,
+			Streams:  []string{w.config.Stream, ">"},
+			Count:    int64(w.config.Concurrency),
+			Block:    w.config.BlockTimeout,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.WithContext(ctx).WithError(err).WithField("stream", w.config.Stream).Error("failed to read stream")
+			w.sleep(ctx, time.Second)
+			continue
+		}
+
+		for _, stream := range streams {
+			for _, entry := range stream.Messages {
+				messages <- &Message{ID: entry.ID, Stream: stream.Stream, Values: entry.Values}
+			}
+		}
+	}
+}
+
+// claim takes over entries left pending by crashed consumers and dead-letters those delivered too often.
+func (w *Worker) claim(ctx context.Context, messages chan<- *Message) {
+	for w.sleep(ctx, w.config.ClaimInterval) {
+		w.claimPending(ctx, messages)
+	}
+}
+
+// claimPending pages through the entries idle for ClaimMinIdle with XPENDING and takes them over with XCLAIM.
+// XAUTOCLAIM isn't used since go-redis v8 fails to parse its three element reply from redis 7.
+func (w *Worker) claimPending(ctx context.Context, messages chan<- *Message) {
+	client := w.client(ctx)
+
+	start := "-"
+	for ctx.Err() == nil {
+		pending, err := client.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream: w.config.Stream,
+			Group:  w.config.Group,
+			Idle:   w.config.ClaimMinIdle,
+			Start:  start,
+			End:    "+",
+			Count:  claimBatchSize,
+		}).Result()
+		if err != nil {
+			log.WithContext(ctx).WithError(err).WithField("stream", w.config.Stream).Error("failed to list pending entries")
+			return
+		}
+
+		ids := make([]string, 0, len(pending))
+		for _, entry := range pending {
+			if entry.RetryCount >= w.config.MaxDeliveries {
+				w.deadLetter(ctx, entry)
+				continue
+			}
+			ids = append(ids, entry.ID)
+		}
+
+		if len(ids) > 0 {
+			// XCLAIM checks the idle time again, so entries taken by another worker in between are skipped
+			claimed, err := client.XClaim(ctx, &redis.XClaimArgs{
+				Stream:   w.config.Stream,
+				Group:    w.config.Group,
+				Consumer: w.config.Consumer,
+				MinIdle:  w.config.ClaimMinIdle,
+				Messages: ids,
+			}).Result()
+			if err != nil {
+				log.WithContext(ctx).WithError(err).WithField("stream", w.config.Stream).Error("failed to claim pending entries")
+				return
+			}
+			for _, entry := range claimed {
+				messages <- &Message{ID: entry.ID, Stream: w.config.Stream, Values: entry.Values}
+			}
+		}
+
+		if len(pending) < claimBatchSize {
+			return
+		}
+		var ok bool
+		start, ok = nextID(pending[len(pending)-1].ID)
+		if !ok {
+			return
+		}
+	}
+}
+
+// nextID returns the smallest entry id after id, since XPENDING ranges are inclusive.
+func nextID(id string) (string, bool) {
+	ms, seq, ok := strings.Cut(id, "-")
+	if !ok {
+		return "", false
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return "", false
+	}
+	if n == math.MaxUint64 {
+		m, err := strconv.ParseUint(ms, 10, 64)
+		if err != nil {
+			return "", false
+		}
+		return strconv.FormatUint(m+1, 10) + "-0", true
+	}
+
+	return ms + "-" + strconv.FormatUint(n+1, 10), true
+}
+
+func (w *Worker) deadLetter(ctx context.Context, pending redis.XPendingExt) {
+	client := w.client(ctx)
+	logger := log.WithContext(ctx).WithFields(map[string]interface{}{
+		"stream":   w.config.Stream,
+		"entry_id": pending.ID,
+	})
+
+	entries, err := client.XRangeN(ctx, w.config.Stream, pending.ID, pending.ID, 1).Result()
+	if err != nil {
+		logger.WithError(err).Error("failed to load entry for dead-lettering")
+		return
+	}
+
+	if len(entries) > 0 {
+		values := entries[0].Values
+		values["dead_letter_source_id"] = pending.ID
+		values["dead_letter_source_stream"] = w.config.Stream
+		values["dead_letter_deliveries"] = pending.RetryCount
+
+		err = client.XAdd(ctx, &redis.XAddArgs{Stream: w.config.DeadLetterStream, Values: values}).Err()
+		if err != nil {
+			logger.WithError(err).Error("failed to dead-letter entry")
+			return
+		}
+	}
+
+	if err = client.XAck(ctx, w.config.Stream, w.config.Group, pending.ID).Err(); err != nil {
+		logger.WithError(err).Error("failed to ack dead-lettered entry")
+		return
+	}
+	logger.Warn("entry moved to dead letter stream")
+}
+
+func (w *Worker) process(ctx context.Context, msg *Message) {
+	carrier := propagation.MapCarrier{}
+	for k, v := range msg.Values {
+		if s, ok := v.(string); ok {
+			carrier[k] = s
+		}
+	}
+	ctx = otel.GetTextMapPropagator().Extract(ctx, carrier)
+
+	ctx, span := tracer.Start(ctx, msg.Stream+" process", trace.WithSpanKind(trace.SpanKindConsumer))
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("messaging.system", "redis"),
+		attribute.String("messaging.destination.name", msg.Stream),
+		attribute.String("messaging.consumer.group.name", w.config.Group),
+		attribute.String("messaging.message.id", msg.ID),
+	)
+
+	spanCtx := trace.SpanContextFromContext(ctx)
+	ctx = context.WithValue(ctx, log.TraceIdKey, spanCtx.TraceID().String())
+	ctx = context.WithValue(ctx, log.SpanIdKey, spanCtx.SpanID().String())
+
+	err := w.handle(ctx, msg)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		log.WithContext(ctx).WithError(err).WithFields(map[string]interface{}{
+			"stream":   msg.Stream,
+			"entry_id": msg.ID,
+		}).Error("failed to handle stream entry")
+		return
+	}
+
+	if err = w.client(ctx).XAck(ctx, msg.Stream, w.config.Group, msg.ID).Err(); err != nil {
+		log.WithContext(ctx).WithError(err).WithField("entry_id", msg.ID).Error("failed to ack stream entry")
+	}
+}
+
+func (w *Worker) handle(ctx context.Context, msg *Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return w.handler(ctx, msg)
+}
+
+// sleep waits for d and reports whether the worker is still running.
+func (w *Worker) sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
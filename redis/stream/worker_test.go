@@ -0,0 +1,227 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	testStream = "orders"
+	testGroup  = "billing"
+)
+
+func newTestWorker(t *testing.T, config WorkerConfig, handler Handler) (*Worker, *redis.Client) {
+	t.Helper()
+
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	config.Stream = testStream
+	config.Group = testGroup
+	config.Consumer = "worker"
+	config.StartID = "0"
+	config.BlockTimeout = 20 * time.Millisecond
+	config.ClaimMinIdle = 20 * time.Millisecond
+	config.ClaimInterval = 20 * time.Millisecond
+
+	w := NewWorker(config, handler)
+	w.client = func(ctx context.Context) *redis.Client { return client }
+
+	err := client.XGroupCreateMkStream(context.Background(), testStream, testGroup, "0").Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return w, client
+}
+
+// readAsCrashedConsumer delivers every new entry to a consumer that never acknowledges them.
+func readAsCrashedConsumer(t *testing.T, client *redis.Client) {
+	t.Helper()
+
+	_, err := client.XReadGroup(context.Background(), &redis.XReadGroupArgs{
+		Group:    testGroup,
+		Consumer: "crashed",
+		Streams:  []string{testStream, ">"},
+		Count:    1000,
+		Block:    -1,
+	}).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func addEntries(t *testing.T, client *redis.Client, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		err := client.XAdd(context.Background(), &redis.XAddArgs{Stream: testStream, Values: map[string]interface{}{"n": i}}).Err()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func pendingCount(t *testing.T, client *redis.Client) int64 {
+	t.Helper()
+
+	pending, err := client.XPending(context.Background(), testStream, testGroup).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pending.Count
+}
+
+func TestWorkerClaimsEntriesOfCrashedConsumer(t *testing.T) {
+	var mu sync.Mutex
+	handled := map[string]int{}
+
+	w, client := newTestWorker(t, WorkerConfig{}, func(ctx context.Context, msg *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		handled[msg.ID]++
+		return nil
+	})
+
+	addEntries(t, client, 3)
+	readAsCrashedConsumer(t, client)
+
+	err := w.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	waitFor(t, "claimed entries to be acknowledged", func() bool { return pendingCount(t, client) == 0 })
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(handled) != 3 {
+		t.Errorf("handled %d entries, want 3", len(handled))
+	}
+}
+
+func TestWorkerRetriesFailedEntries(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+
+	w, client := newTestWorker(t, WorkerConfig{}, func(ctx context.Context, msg *Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts == 1 {
+			return errors.New("temporary failure")
+		}
+		return nil
+	})
+
+	err := w.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	addEntries(t, client, 1)
+
+	waitFor(t, "the retried entry to be acknowledged", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return attempts >= 2 && pendingCount(t, client) == 0
+	})
+}
+
+func TestWorkerDeadLettersAfterMaxDeliveries(t *testing.T) {
+	w, client := newTestWorker(t, WorkerConfig{MaxDeliveries: 2}, func(ctx context.Context, msg *Message) error {
+		panic("always fails")
+	})
+
+	err := w.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	addEntries(t, client, 1)
+
+	waitFor(t, "the entry to be dead-lettered", func() bool {
+		n, err := client.XLen(context.Background(), testStream+":dead").Result()
+		return err == nil && n == 1
+	})
+
+	entries, err := client.XRange(context.Background(), testStream+":dead", "-", "+").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := entries[0].Values
+	if values["n"] != "0" || values["dead_letter_source_stream"] != testStream || values["dead_letter_deliveries"] != "2" {
+		t.Errorf("unexpected dead letter %v", values)
+	}
+	if pendingCount(t, client) != 0 {
+		t.Error("dead-lettered entry is still pending")
+	}
+}
+
+func TestWorkerPagesThroughPendingEntries(t *testing.T) {
+	w, client := newTestWorker(t, WorkerConfig{MaxDeliveries: 1}, func(ctx context.Context, msg *Message) error {
+		return nil
+	})
+
+	// more entries than one XPENDING page, all delivered once to a crashed consumer
+	total := claimBatchSize*2 + 7
+	addEntries(t, client, total)
+	readAsCrashedConsumer(t, client)
+
+	time.Sleep(w.config.ClaimMinIdle)
+	messages := make(chan *Message, total)
+	w.claimPending(context.Background(), messages)
+	close(messages)
+
+	if len(messages) != 0 {
+		t.Errorf("claimed %d entries, want all to be dead-lettered", len(messages))
+	}
+	n, err := client.XLen(context.Background(), testStream+":dead").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(total) {
+		t.Errorf("dead-lettered %d entries, want %d", n, total)
+	}
+}
+
+func TestNextID(t *testing.T) {
+	tests := map[string]string{
+		"1-0":                           "1-1",
+		"1526919030474-55":              "1526919030474-56",
+		fmt.Sprintf("7-%d", ^uint64(0)): "8-0",
+	}
+	for id, want := range tests {
+		next, ok := nextID(id)
+		if !ok || next != want {
+			t.Errorf("nextID(%s) = %s, %v, want %s", id, next, ok, want)
+		}
+	}
+
+	if _, ok := nextID("invalid"); ok {
+		t.Error("nextID(invalid) should fail")
+	}
+}
@@ -2,11 +2,18 @@ package snowflake
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
 	"github.com/sony/sonyflake"
 	"github.com/www-xu/spark"
-	"math/rand"
 )
 
+// ErrClockMovedBackwards is returned by NextID when the clock moved backwards further than max_clock_backwards.
+var ErrClockMovedBackwards = errors.New("snowflake clock moved backwards")
+
 var instance *Snowflake
 
 func init() {
@@ -17,9 +24,15 @@ func init() {
 }
 
 type Snowflake struct {
-	ctx      *spark.ApplicationContext
-	setting  *sonyflake.Settings
-	instance *sonyflake.Sonyflake
+	ctx       *spark.ApplicationContext
+	config    *SnowflakeConfig
+	setting   *sonyflake.Settings
+	instance  *sonyflake.Sonyflake
+	lease     Lease
+	generator IDGenerator
+
+	mu       sync.Mutex
+	lastTime int64 // wall clock of the latest NextID in nanoseconds
 }
 
 func NewSnowflake() *Snowflake {
@@ -27,20 +40,55 @@ func NewSnowflake() *Snowflake {
 }
 
 func (c *Snowflake) Instantiate() (err error) {
-	c.setting = &sonyflake.Settings{
-		MachineID: func() (uint16, error) {
-			return uint16(rand.Uint32()), nil
-		},
+	err = c.ctx.UnmarshalKey("snowflake", &c.config)
+	if err != nil {
+		return err
+	}
+	if c.config == nil {
+		c.config = &SnowflakeConfig{}
+	}
+
+	c.setting = &sonyflake.Settings{}
+
+	if c.config.StartTime != "" {
+		c.setting.StartTime, err = parseStartTime(c.config.StartTime)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.setting.MachineID, err = c.machineID()
+	if err != nil {
+		return err
 	}
 
 	c.instance, err = sonyflake.New(*c.setting)
-	if instance == nil || err != nil {
+	if err != nil {
+		return fmt.Errorf("create snowflake: %w", err)
+	}
+
+	c.generator, err = c.newGenerator()
+	if err != nil {
 		return err
 	}
 
 	return nil
 }
 
+func parseStartTime(value string) (time.Time, error) {
+	startTime, err := time.Parse(time.DateOnly, value)
+	if err == nil {
+		return startTime, nil
+	}
+
+	startTime, err = time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid snowflake start_time %s: %w", value, err)
+	}
+
+	return startTime, nil
+}
+
 func Get(ctx context.Context) *sonyflake.Sonyflake {
 	return instance.Get(ctx)
 }
@@ -49,6 +97,52 @@ func (c *Snowflake) Get(ctx context.Context) *sonyflake.Sonyflake {
 	return c.instance
 }
 
+// NextID generates a new id. Unlike calling the sonyflake instance directly,
+// it fails when the machine id lease is lost or the clock moved backwards too far.
+func NextID(ctx context.Context) (ID, error) {
+	return instance.NextID(ctx)
+}
+
+func (c *Snowflake) NextID(ctx context.Context) (ID, error) {
+	if c.lease != nil {
+		err := c.lease.Err()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err := c.checkClock()
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := c.instance.NextID()
+	if err != nil {
+		return 0, err
+	}
+
+	return ID(id), nil
+}
+
+func (c *Snowflake) checkClock() error {
+	now := time.Now().UnixNano()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now < c.lastTime {
+		backwards := time.Duration(c.lastTime - now)
+		if backwards > c.config.MaxClockBackwards {
+			return fmt.Errorf("%w by %s", ErrClockMovedBackwards, backwards)
+		}
+		return nil
+	}
+
+	c.lastTime = now
+
+	return nil
+}
+
 func (c *Snowflake) Close() error {
 	return nil
 }
@@ -64,6 +158,11 @@ func (c *Snowflake) AfterInit(applicationContext *spark.ApplicationContext) erro
 }
 
 func (c *Snowflake) BeforeStop() {
+	// release the lease while the redis client is still open
+	if c.lease != nil {
+		c.lease.Release()
+	}
+
 	return
 }
 
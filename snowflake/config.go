@@ -0,0 +1,31 @@
+package snowflake
+
+import "time"
+
+const (
+	StrategyRandom   = "random"
+	StrategyStatic   = "static"
+	StrategyIP       = "ip"
+	StrategyHostname = "hostname"
+	StrategyRedis    = "redis"
+)
+
+type MachineIDConfig struct {
+	Strategy string        `mapstructure:"strategy"`  // random, static, ip, hostname or redis (import snowflake/redislease), defaults to random
+	ID       uint16        `mapstructure:"id"`        // used by the static strategy
+	Key      string        `mapstructure:"key"`       // redis lease key prefix, defaults to snowflake:machine_id
+	LeaseTTL time.Duration `mapstructure:"lease_ttl"` // redis lease ttl, renewed every third of it
+	Max      uint16        `mapstructure:"max"`       // redis leases are taken from [0, max], defaults to 65535
+}
+
+type SnowflakeConfig struct {
+	// Generator selects the IDGenerator used by NextString: sonyflake, ulid or uuidv7, defaults to sonyflake.
+	Generator string `mapstructure:"generator"`
+	// StartTime is the epoch of generated ids, formatted as 2006-01-02 or RFC3339.
+	// It must never change once ids are issued. Defaults to sonyflake's 2014-09-01.
+	StartTime string          `mapstructure:"start_time"`
+	MachineID MachineIDConfig `mapstructure:"machine_id"`
+	// MaxClockBackwards is how far the clock may move backwards before NextID fails with ErrClockMovedBackwards.
+	// Within it sonyflake keeps issuing ids from the last timestamp.
+	MaxClockBackwards time.Duration `mapstructure:"max_clock_backwards"`
+}
@@ -0,0 +1,108 @@
+package snowflake
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
+)
+
+const (
+	GeneratorSonyflake = "sonyflake"
+	GeneratorULID      = "ulid"
+	GeneratorUUIDv7    = "uuidv7"
+)
+
+// IDGenerator generates ids in their string form. Ids from one generator are ordered by
+// creation time, and ids created within the same millisecond are still increasing.
+type IDGenerator interface {
+	NextString(ctx context.Context) (string, error)
+}
+
+// ULIDGenerator generates ULIDs, 26 character strings that sort lexicographically.
+type ULIDGenerator struct {
+	mu      sync.Mutex
+	entropy *ulid.MonotonicEntropy
+}
+
+func NewULIDGenerator() *ULIDGenerator {
+	return &ULIDGenerator{
+		entropy: ulid.Monotonic(rand.Reader, 0),
+	}
+}
+
+// NextULID increments the random part of the previous ULID within the same millisecond.
+func (g *ULIDGenerator) NextULID() (ulid.ULID, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return ulid.New(ulid.Timestamp(time.Now()), g.entropy)
+}
+
+func (g *ULIDGenerator) NextString(ctx context.Context) (string, error) {
+	id, err := g.NextULID()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// UUIDv7Generator generates version 7 UUIDs, which fit uuid columns and sort by time.
+type UUIDv7Generator struct{}
+
+func NewUUIDv7Generator() UUIDv7Generator {
+	return UUIDv7Generator{}
+}
+
+// NextUUID relies on uuid.NewV7, which uses a counter within the same millisecond.
+func (UUIDv7Generator) NextUUID() (uuid.UUID, error) {
+	return uuid.NewV7()
+}
+
+func (g UUIDv7Generator) NextString(ctx context.Context) (string, error) {
+	id, err := g.NextUUID()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// NextString generates a sonyflake id in its decimal form.
+func (c *Snowflake) NextString(ctx context.Context) (string, error) {
+	id, err := c.NextID(ctx)
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+func (c *Snowflake) newGenerator() (IDGenerator, error) {
+	switch c.config.Generator {
+	case "", GeneratorSonyflake:
+		return c, nil
+	case GeneratorULID:
+		return NewULIDGenerator(), nil
+	case GeneratorUUIDv7:
+		return NewUUIDv7Generator(), nil
+	default:
+		return nil, fmt.Errorf("unknown id generator: %s", c.config.Generator)
+	}
+}
+
+// Generator returns the generator selected by snowflake.generator.
+func Generator(ctx context.Context) IDGenerator {
+	return instance.Generator(ctx)
+}
+
+func (c *Snowflake) Generator(ctx context.Context) IDGenerator {
+	return c.generator
+}
+
+// NextString generates an id with the configured generator.
+func NextString(ctx context.Context) (string, error) {
+	return instance.generator.NextString(ctx)
+}
@@ -0,0 +1,89 @@
+package snowflake
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
+)
+
+func TestULIDGeneratorMonotonic(t *testing.T) {
+	generator := NewULIDGenerator()
+
+	// generate until a run of ids shares a millisecond, which happens right away on any machine
+	var previous ulid.ULID
+	sameMillisecond := 0
+	for i := 0; i < 10000 && sameMillisecond < 100; i++ {
+		id, err := generator.NextULID()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if i > 0 {
+			if id.Compare(previous) <= 0 {
+				t.Fatalf("%s isn't after %s", id, previous)
+			}
+			if id.String() <= previous.String() {
+				t.Fatalf("%s doesn't sort after %s", id, previous)
+			}
+			if id.Time() == previous.Time() {
+				sameMillisecond++
+			}
+		}
+		previous = id
+	}
+
+	if sameMillisecond == 0 {
+		t.Error("no two ULIDs were generated in the same millisecond")
+	}
+}
+
+func TestIDGenerators(t *testing.T) {
+	ctx := context.Background()
+	generators := map[string]IDGenerator{
+		GeneratorULID:   NewULIDGenerator(),
+		GeneratorUUIDv7: NewUUIDv7Generator(),
+	}
+
+	for name, generator := range generators {
+		previous := ""
+		for i := 0; i < 1000; i++ {
+			id, err := generator.NextString(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id <= previous {
+				t.Fatalf("%s: %s doesn't sort after %s", name, id, previous)
+			}
+			previous = id
+		}
+	}
+
+	id, _ := generators[GeneratorUUIDv7].NextString(ctx)
+	parsed, err := uuid.Parse(id)
+	if err != nil || parsed.Version() != 7 {
+		t.Errorf("uuid %s: version %d, %v", id, parsed.Version(), err)
+	}
+	sec, nsec := parsed.Time().UnixTime()
+	if since := time.Since(time.Unix(sec, nsec)); since < 0 || since > time.Second {
+		t.Errorf("uuid time is %s ago", since)
+	}
+}
+
+func TestNewGenerator(t *testing.T) {
+	for _, name := range []string{"", GeneratorSonyflake, GeneratorULID, GeneratorUUIDv7} {
+		c := &Snowflake{config: &SnowflakeConfig{Generator: name}}
+		_, err := c.newGenerator()
+		if err != nil {
+			t.Errorf("%q: %v", name, err)
+		}
+	}
+
+	c := &Snowflake{config: &SnowflakeConfig{Generator: "uuidv4"}}
+	_, err := c.newGenerator()
+	if err == nil {
+		t.Error("want an error for an unknown generator")
+	}
+}
@@ -0,0 +1,221 @@
+package snowflake
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sony/sonyflake"
+)
+
+// ID is a sonyflake id. It is encoded as a decimal string in JSON,
+// since JavaScript numbers lose precision above 2^53.
+type ID uint64
+
+const (
+	base32Alphabet = "ybndrfg8ejkmcpqxot1uwisza345h769"
+	base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+)
+
+var (
+	base32Index = alphabetIndex(base32Alphabet)
+	base58Index = alphabetIndex(base58Alphabet)
+)
+
+// ErrInvalidID is returned when parsing a malformed id.
+var ErrInvalidID = errors.New("invalid snowflake id")
+
+func alphabetIndex(alphabet string) [256]byte {
+	var index [256]byte
+	for i := range index {
+		index[i] = 0xFF
+	}
+	for i := 0; i < len(alphabet); i++ {
+		index[alphabet[i]] = byte(i)
+	}
+	return index
+}
+
+func (id ID) Uint64() uint64 {
+	return uint64(id)
+}
+
+func (id ID) String() string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func (id ID) Hex() string {
+	return strconv.FormatUint(uint64(id), 16)
+}
+
+// Base32 encodes the id with the z-base-32 alphabet, which avoids easily confused characters.
+func (id ID) Base32() string {
+	return encode(uint64(id), base32Alphabet)
+}
+
+// Base58 encodes the id with the bitcoin base58 alphabet, the shortest url safe form.
+func (id ID) Base58() string {
+	return encode(uint64(id), base58Alphabet)
+}
+
+func encode(value uint64, alphabet string) string {
+	if value == 0 {
+		return alphabet[:1]
+	}
+
+	base := uint64(len(alphabet))
+	buf := make([]byte, 0, 13)
+	for value > 0 {
+		buf = append(buf, alphabet[value%base])
+		value /= base
+	}
+
+	for i, j := 0, len(buf)-1; i < j; i, j = i+1, j-1 {
+		buf[i], buf[j] = buf[j], buf[i]
+	}
+
+	return string(buf)
+}
+
+func decode(value string, alphabet string, index *[256]byte) (ID, error) {
+	if value == "" {
+		return 0, ErrInvalidID
+	}
+
+	base := uint64(len(alphabet))
+	var id uint64
+	for i := 0; i < len(value); i++ {
+		digit := index[value[i]]
+		if digit == 0xFF {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidID, value)
+		}
+
+		next := id*base + uint64(digit)
+		if next/base != id {
+			return 0, fmt.Errorf("%w: %q overflows", ErrInvalidID, value)
+		}
+		id = next
+	}
+
+	return ID(id), nil
+}
+
+func ParseString(value string) (ID, error) {
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidID, value)
+	}
+	return ID(id), nil
+}
+
+func ParseHex(value string) (ID, error) {
+	id, err := strconv.ParseUint(value, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidID, value)
+	}
+	return ID(id), nil
+}
+
+func ParseBase32(value string) (ID, error) {
+	return decode(value, base32Alphabet, &base32Index)
+}
+
+func ParseBase58(value string) (ID, error) {
+	return decode(value, base58Alphabet, &base58Index)
+}
+
+func (id ID) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + id.String() + `"`), nil
+}
+
+// UnmarshalJSON accepts both the string form and a plain number.
+func (id *ID) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	value := string(data)
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		value = value[1 : len(value)-1]
+	}
+
+	parsed, err := ParseString(value)
+	if err != nil {
+		return err
+	}
+	*id = parsed
+
+	return nil
+}
+
+// Scan implements sql.Scanner so that ID can be used in gorm models backed by a BIGINT column.
+func (id *ID) Scan(src any) error {
+	switch value := src.(type) {
+	case nil:
+		*id = 0
+	case int64:
+		*id = ID(value)
+	case uint64:
+		*id = ID(value)
+	case []byte:
+		parsed, err := ParseString(string(value))
+		if err != nil {
+			return err
+		}
+		*id = parsed
+	case string:
+		parsed, err := ParseString(value)
+		if err != nil {
+			return err
+		}
+		*id = parsed
+	default:
+		return fmt.Errorf("can't scan %T into snowflake id", src)
+	}
+
+	return nil
+}
+
+// Value implements driver.Valuer, sonyflake ids use 63 bits so they always fit in int64.
+func (id ID) Value() (driver.Value, error) {
+	return int64(id), nil
+}
+
+// Parts are the fields packed into an id.
+type Parts struct {
+	Time      time.Time
+	Sequence  uint16
+	MachineID uint16
+}
+
+// Decompose extracts the time, sequence and machine id from an id generated with the configured start time.
+func Decompose(id ID) Parts {
+	return instance.Decompose(id)
+}
+
+func (c *Snowflake) Decompose(id ID) Parts {
+	const (
+		maskSequence  = 1<<sonyflake.BitLenSequence - 1
+		maskMachineID = 1<<sonyflake.BitLenMachineID - 1
+		// sonyflake counts time in units of 10ms
+		timeUnit = 10 * time.Millisecond
+	)
+
+	startTime := defaultStartTime
+	if c.setting != nil && !c.setting.StartTime.IsZero() {
+		startTime = c.setting.StartTime
+	}
+
+	elapsed := uint64(id) >> (sonyflake.BitLenSequence + sonyflake.BitLenMachineID)
+
+	return Parts{
+		Time:      startTime.Add(time.Duration(elapsed) * timeUnit),
+		Sequence:  uint16(uint64(id) >> sonyflake.BitLenMachineID & maskSequence),
+		MachineID: uint16(uint64(id) & maskMachineID),
+	}
+}
+
+// defaultStartTime is the epoch sonyflake uses when no start time is configured.
+var defaultStartTime = time.Date(2014, 9, 1, 0, 0, 0, 0, time.UTC)
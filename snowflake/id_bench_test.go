@@ -0,0 +1,112 @@
+package snowflake
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/sony/sonyflake"
+)
+
+// newBenchSnowflake returns a Snowflake without spark, with a static machine id.
+func newBenchSnowflake(b *testing.B) *Snowflake {
+	b.Helper()
+
+	instance, err := sonyflake.New(sonyflake.Settings{
+		MachineID: func() (uint16, error) {
+			return 1, nil
+		},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return &Snowflake{
+		config:   &SnowflakeConfig{},
+		instance: instance,
+	}
+}
+
+// BenchmarkSonyflake is the baseline of calling sonyflake directly. Sonyflake issues at most
+// 256 ids per 10ms per machine, so it sleeps once the sequence of a time slot is used up.
+func BenchmarkSonyflake(b *testing.B) {
+	instance := newBenchSnowflake(b).instance
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		id, err := instance.NextID()
+		if err != nil {
+			b.Fatal(err)
+		}
+		_ = strconv.FormatUint(id, 10)
+	}
+}
+
+func BenchmarkSnowflakeNextString(b *testing.B) {
+	generator := newBenchSnowflake(b)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := generator.NextString(ctx)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkULIDNextString(b *testing.B) {
+	generator := NewULIDGenerator()
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := generator.NextString(ctx)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUUIDv7NextString(b *testing.B) {
+	generator := NewUUIDv7Generator()
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := generator.NextString(ctx)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkIDGeneratorsParallel(b *testing.B) {
+	generators := map[string]IDGenerator{
+		GeneratorSonyflake: newBenchSnowflake(b),
+		GeneratorULID:      NewULIDGenerator(),
+		GeneratorUUIDv7:    NewUUIDv7Generator(),
+	}
+
+	for _, name := range []string{GeneratorSonyflake, GeneratorULID, GeneratorUUIDv7} {
+		generator := generators[name]
+		b.Run(name, func(b *testing.B) {
+			ctx := context.Background()
+
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					_, err := generator.NextString(ctx)
+					if err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
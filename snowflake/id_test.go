@@ -0,0 +1,220 @@
+package snowflake
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/sony/sonyflake"
+)
+
+var testIDs = []ID{0, 1, 31, 32, 57, 58, 1 << 53, 596837473839513601, math.MaxInt64, math.MaxUint64}
+
+func TestEncodings(t *testing.T) {
+	tests := []struct {
+		name   string
+		format func(ID) string
+		parse  func(string) (ID, error)
+	}{
+		{"string", ID.String, ParseString},
+		{"hex", ID.Hex, ParseHex},
+		{"base32", ID.Base32, ParseBase32},
+		{"base58", ID.Base58, ParseBase58},
+	}
+
+	for _, test := range tests {
+		for _, id := range testIDs {
+			encoded := test.format(id)
+			parsed, err := test.parse(encoded)
+			if err != nil {
+				t.Errorf("%s: parse %q: %v", test.name, encoded, err)
+				continue
+			}
+			if parsed != id {
+				t.Errorf("%s: %d encoded as %q parses to %d", test.name, id, encoded, parsed)
+			}
+		}
+	}
+}
+
+func TestKnownEncodings(t *testing.T) {
+	tests := []struct {
+		id     ID
+		base32 string
+		base58 string
+		hex    string
+	}{
+		{0, "y", "1", "0"},
+		{31, "9", "Y", "1f"},
+		{32, "by", "Z", "20"},
+		{57, "b3", "z", "39"},
+		{58, "b4", "21", "3a"},
+		{math.MaxUint64, "x999999999999", "jpXCZedGfVQ", "ffffffffffffffff"},
+	}
+
+	for _, test := range tests {
+		if got := test.id.Base32(); got != test.base32 {
+			t.Errorf("Base32(%d) = %s, want %s", test.id, got, test.base32)
+		}
+		if got := test.id.Base58(); got != test.base58 {
+			t.Errorf("Base58(%d) = %s, want %s", test.id, got, test.base58)
+		}
+		if got := test.id.Hex(); got != test.hex {
+			t.Errorf("Hex(%d) = %s, want %s", test.id, got, test.hex)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		parse func(string) (ID, error)
+		value string
+	}{
+		{"string", ParseString, ""},
+		{"string", ParseString, "-1"},
+		{"string", ParseString, "18446744073709551616"},
+		{"hex", ParseHex, "xyz"},
+		{"base32", ParseBase32, ""},
+		{"base32", ParseBase32, "l"},             // not in z-base-32
+		{"base32", ParseBase32, "oyyyyyyyyyyyy"}, // math.MaxUint64 + 1
+		{"base58", ParseBase58, "0"},
+		{"base58", ParseBase58, "O"},
+		{"base58", ParseBase58, "I"},
+		{"base58", ParseBase58, "l"},
+		{"base58", ParseBase58, "jpXCZedGfVR"}, // math.MaxUint64 + 1
+	}
+
+	for _, test := range tests {
+		_, err := test.parse(test.value)
+		if !errors.Is(err, ErrInvalidID) {
+			t.Errorf("%s: parse %q: err = %v, want ErrInvalidID", test.name, test.value, err)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	type order struct {
+		ID     ID  `json:"id"`
+		Parent *ID `json:"parent"`
+	}
+
+	parent := ID(math.MaxInt64)
+	data, err := json.Marshal(order{ID: 596837473839513601, Parent: &parent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"id":"596837473839513601","parent":"9223372036854775807"}` {
+		t.Errorf("json = %s", data)
+	}
+
+	var decoded order
+	err = json.Unmarshal(data, &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.ID != 596837473839513601 || decoded.Parent == nil || *decoded.Parent != parent {
+		t.Errorf("decoded = %+v", decoded)
+	}
+
+	// numbers and null are accepted too
+	decoded = order{}
+	err = json.Unmarshal([]byte(`{"id":42,"parent":null}`), &decoded)
+	if err != nil || decoded.ID != 42 || decoded.Parent != nil {
+		t.Errorf("decoded = %+v, %v", decoded, err)
+	}
+
+	err = json.Unmarshal([]byte(`{"id":"abc"}`), &decoded)
+	if !errors.Is(err, ErrInvalidID) {
+		t.Errorf("err = %v, want ErrInvalidID", err)
+	}
+}
+
+func TestScanValue(t *testing.T) {
+	for _, id := range []ID{0, 1, 596837473839513601, math.MaxInt64} {
+		value, err := id.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := value.(int64); !ok || !driver.IsValue(value) {
+			t.Errorf("Value(%d) = %T, want int64", id, value)
+		}
+
+		var scanned ID
+		err = scanned.Scan(value)
+		if err != nil || scanned != id {
+			t.Errorf("Scan(%v) = %d, %v, want %d", value, scanned, err, id)
+		}
+	}
+
+	for _, src := range []any{"42", []byte("42"), uint64(42)} {
+		var scanned ID
+		err := scanned.Scan(src)
+		if err != nil || scanned != 42 {
+			t.Errorf("Scan(%#v) = %d, %v, want 42", src, scanned, err)
+		}
+	}
+
+	scanned := ID(42)
+	err := scanned.Scan(nil)
+	if err != nil || scanned != 0 {
+		t.Errorf("Scan(nil) = %d, %v, want 0", scanned, err)
+	}
+
+	err = scanned.Scan(4.2)
+	if err == nil {
+		t.Error("want an error scanning a float")
+	}
+	err = scanned.Scan("abc")
+	if !errors.Is(err, ErrInvalidID) {
+		t.Errorf("err = %v, want ErrInvalidID", err)
+	}
+}
+
+func TestDecompose(t *testing.T) {
+	id := ID(100<<(sonyflake.BitLenSequence+sonyflake.BitLenMachineID) | 5<<sonyflake.BitLenMachineID | 7)
+
+	parts := (&Snowflake{}).Decompose(id)
+	if !parts.Time.Equal(defaultStartTime.Add(time.Second)) || parts.Sequence != 5 || parts.MachineID != 7 {
+		t.Errorf("parts = %+v", parts)
+	}
+
+	startTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	parts = (&Snowflake{setting: &sonyflake.Settings{StartTime: startTime}}).Decompose(id)
+	if !parts.Time.Equal(startTime.Add(time.Second)) {
+		t.Errorf("time = %s, want 1s after the start time", parts.Time)
+	}
+}
+
+func TestDecomposeGenerated(t *testing.T) {
+	setting := &sonyflake.Settings{
+		StartTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		MachineID: func() (uint16, error) {
+			return 513, nil
+		},
+	}
+	instance, err := sonyflake.New(*setting)
+	if err != nil {
+		t.Fatal(err)
+	}
+	generator := &Snowflake{config: &SnowflakeConfig{}, setting: setting, instance: instance}
+
+	before := time.Now()
+	id, err := generator.NextID(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := generator.Decompose(id)
+	if parts.MachineID != 513 {
+		t.Errorf("machine id = %d, want 513", parts.MachineID)
+	}
+	// sonyflake truncates time to 10ms
+	if parts.Time.Before(before.Add(-10*time.Millisecond)) || parts.Time.After(time.Now()) {
+		t.Errorf("time = %s, want about %s", parts.Time, before)
+	}
+}
@@ -0,0 +1,86 @@
+package snowflake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrMachineIDLeaseLost is returned by NextID after the lease of the machine id expired,
+// since another instance may have taken the same id.
+var ErrMachineIDLeaseLost = errors.New("snowflake machine id lease lost")
+
+// Lease holds a machine id taken from a shared store, e.g. redis, until it is released.
+type Lease interface {
+	// Acquire takes a free machine id and keeps it alive in the background.
+	Acquire(ctx context.Context) (uint16, error)
+	// Err returns ErrMachineIDLeaseLost once the lease could not be renewed.
+	Err() error
+	// Release stops renewing and frees the machine id.
+	Release()
+}
+
+var leases = map[string]func(config MachineIDConfig) Lease{}
+
+// RegisterLease makes a lease store available as machine id strategy under name, it must be called before spark.Init.
+func RegisterLease(strategy string, newLease func(config MachineIDConfig) Lease) {
+	leases[strategy] = newLease
+}
+
+// machineID returns the machine id function for the configured strategy,
+// nil for the ip strategy means sonyflake's default of the lower 16 bits of the private ip, which is unique per pod within a /16 network.
+func (c *Snowflake) machineID() (func() (uint16, error), error) {
+	config := c.config.MachineID
+
+	switch config.Strategy {
+	case "", StrategyRandom:
+		return func() (uint16, error) {
+			return uint16(rand.Uint32()), nil
+		}, nil
+	case StrategyIP:
+		return nil, nil
+	case StrategyStatic:
+		return func() (uint16, error) {
+			return config.ID, nil
+		}, nil
+	case StrategyHostname:
+		return hostnameOrdinal, nil
+	}
+
+	newLease, ok := leases[config.Strategy]
+	if !ok {
+		if config.Strategy == StrategyRedis {
+			return nil, errors.New("snowflake machine id strategy redis needs github.com/www-xu/spark/snowflake/redislease to be imported")
+		}
+		return nil, fmt.Errorf("unknown snowflake machine id strategy: %s", config.Strategy)
+	}
+	c.lease = newLease(config)
+
+	return func() (uint16, error) {
+		return c.lease.Acquire(context.Background())
+	}, nil
+}
+
+// hostnameOrdinal parses the ordinal suffix of a StatefulSet pod hostname, e.g. 3 from id-generator-3.
+func hostnameOrdinal() (uint16, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return 0, err
+	}
+
+	index := strings.LastIndex(hostname, "-")
+	if index < 0 {
+		return 0, fmt.Errorf("hostname %s has no ordinal suffix", hostname)
+	}
+
+	ordinal, err := strconv.ParseUint(hostname[index+1:], 10, 16)
+	if err != nil {
+		return 0, fmt.Errorf("hostname %s has no ordinal suffix: %w", hostname, err)
+	}
+
+	return uint16(ordinal), nil
+}
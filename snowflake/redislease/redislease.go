@@ -0,0 +1,154 @@
+// Package redislease registers the redis machine id strategy of snowflake, which leases ids
+// from [0, max] with SET NX and renews them until the application stops.
+package redislease
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sony/sonyflake"
+	"github.com/www-xu/spark/log"
+	sparkRedis "github.com/www-xu/spark/redis"
+	"github.com/www-xu/spark/snowflake"
+)
+
+const (
+	defaultLeaseKey = "snowflake:machine_id"
+	defaultLeaseTTL = 30 * time.Second
+
+	// maxMachineID is the largest machine id sonyflake can encode
+	maxMachineID = 1<<sonyflake.BitLenMachineID - 1
+)
+
+func init() {
+	snowflake.RegisterLease(snowflake.StrategyRedis, func(config snowflake.MachineIDConfig) snowflake.Lease {
+		return &lease{config: config}
+	})
+}
+
+var (
+	renewScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("pexpire", KEYS[1], ARGV[2])
+end
+return 0`)
+
+	releaseScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+end
+return 0`)
+)
+
+// lease holds a machine id in redis and renews it until released.
+type lease struct {
+	config snowflake.MachineIDConfig
+	key    string
+	value  string
+	lost   chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func (l *lease) Acquire(ctx context.Context) (uint16, error) {
+	if l.config.LeaseTTL <= 0 {
+		l.config.LeaseTTL = defaultLeaseTTL
+	}
+	if l.config.Key == "" {
+		l.config.Key = defaultLeaseKey
+	}
+	if l.config.Max == 0 {
+		l.config.Max = maxMachineID
+	}
+
+	hostname, _ := os.Hostname()
+	l.value = fmt.Sprintf("%s:%d:%d", hostname, os.Getpid(), time.Now().UnixNano())
+
+	client := sparkRedis.Get(ctx)
+	size := int(l.config.Max) + 1
+	// start from a random id so that instances booting together don't race for the same keys
+	offset := rand.Intn(size)
+	for i := 0; i < size; i++ {
+		id := uint16((offset + i) % size)
+		key := fmt.Sprintf("%s:%d", l.config.Key, id)
+
+		ok, err := client.SetNX(ctx, key, l.value, l.config.LeaseTTL).Result()
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			l.key = key
+			l.keepAlive(client)
+			return id, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no free snowflake machine id under %s", l.config.Key)
+}
+
+func (l *lease) keepAlive(client *redis.Client) {
+	ctx, cancel := context.WithCancel(context.Background())
+	l.cancel = cancel
+	l.lost = make(chan struct{})
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+
+		ticker := time.NewTicker(l.config.LeaseTTL / 3)
+		defer ticker.Stop()
+
+		renewedAt := time.Now()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			renewed, err := renewScript.Run(ctx, client, []string{l.key}, l.value, l.config.LeaseTTL.Milliseconds()).Int()
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.WithContext(ctx).WithError(err).WithField("key", l.key).Warn("failed to renew snowflake machine id lease")
+				// keep retrying until the lease has expired in redis and may be taken by another instance
+				if time.Since(renewedAt) < l.config.LeaseTTL {
+					continue
+				}
+			}
+			if err != nil || renewed == 0 {
+				log.WithContext(ctx).WithField("key", l.key).Error("snowflake machine id lease lost")
+				close(l.lost)
+				return
+			}
+			renewedAt = time.Now()
+		}
+	}()
+}
+
+func (l *lease) Err() error {
+	select {
+	case <-l.lost:
+		return snowflake.ErrMachineIDLeaseLost
+	default:
+		return nil
+	}
+}
+
+func (l *lease) Release() {
+	if l.cancel == nil {
+		return
+	}
+
+	l.cancel()
+	l.wg.Wait()
+
+	_ = releaseScript.Run(context.Background(), sparkRedis.Get(context.Background()), []string{l.key}, l.value).Err()
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/www-xu/spark"
+)
+
+type Component struct {
+	ctx     *spark.ApplicationContext
+	config  *Config
+	buckets map[string]Bucket
+}
+
+func NewComponent() *Component {
+	return &Component{}
+}
+
+var instance *Component
+
+func init() {
+	instance = NewComponent()
+
+	spark.RegisterApplicationInitEventListener(instance)
+	spark.RegisterApplicationStopEventListener(instance)
+}
+
+func (c *Component) Instantiate() error {
+	err := c.ctx.UnmarshalKey("storage", &c.config)
+	if err != nil {
+		return err
+	}
+
+	if c.config == nil {
+		return errors.New("storage config isn't found")
+	}
+
+	c.buckets = make(map[string]Bucket, len(c.config.Buckets))
+	for name, bucketConfig := range c.config.Buckets {
+		bucket, err := Open(context.Background(), bucketConfig)
+		if err != nil {
+			return fmt.Errorf("storage bucket %s: %w", name, err)
+		}
+		c.buckets[name] = bucket
+	}
+
+	return nil
+}
+
+// GetBucket returns the bucket declared as name in storage.buckets.
+func GetBucket(ctx context.Context, name string) (Bucket, error) {
+	return instance.GetBucket(ctx, name)
+}
+
+func (c *Component) GetBucket(ctx context.Context, name string) (Bucket, error) {
+	bucket, ok := c.buckets[name]
+	if !ok {
+		return nil, fmt.Errorf("storage bucket %s isn't declared", name)
+	}
+
+	return bucket, nil
+}
+
+func (c *Component) Close() error {
+
+	return nil
+}
+
+func (c *Component) BeforeInit() error {
+	return nil
+}
+
+func (c *Component) AfterInit(applicationContext *spark.ApplicationContext) error {
+	c.ctx = applicationContext
+
+	return c.Instantiate()
+}
+
+func (c *Component) BeforeStop() {
+	return
+}
+
+func (c *Component) AfterStop() {
+	_ = c.Close()
+
+	return
+}
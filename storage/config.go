@@ -0,0 +1,25 @@
+package storage
+
+type BucketConfig struct {
+	Driver string `mapstructure:"driver"` // local, s3 or oss, the s3 and oss drivers must be imported
+	Bucket string `mapstructure:"bucket"` // bucket name for s3 and oss
+
+	// s3 and oss
+	Endpoint string `mapstructure:"endpoint"` // e.g. s3.amazonaws.com, localhost:9000 or oss-cn-hangzhou.aliyuncs.com
+	Region   string `mapstructure:"region"`
+	// AccessKeyEnv and SecretKeyEnv name the environment variables holding the credentials,
+	// they default to the provider's usual variables.
+	AccessKeyEnv string `mapstructure:"access_key_env"`
+	SecretKeyEnv string `mapstructure:"secret_key_env"`
+	Insecure     bool   `mapstructure:"insecure"`   // s3 only, use http instead of https
+	PathStyle    bool   `mapstructure:"path_style"` // s3 only, needed by MinIO and most stand-ins
+
+	// local
+	Dir       string `mapstructure:"dir"`        // root directory of the objects
+	BaseURL   string `mapstructure:"base_url"`   // where LocalBucket.Handler is served, used by SignURL
+	SecretEnv string `mapstructure:"secret_env"` // environment variable holding the URL signing secret
+}
+
+type Config struct {
+	Buckets map[string]BucketConfig `mapstructure:"buckets"` // key is the name used by GetBucket
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// OpenFunc creates a bucket from its config.
+type OpenFunc func(ctx context.Context, config BucketConfig) (Bucket, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]OpenFunc{}
+)
+
+// Register makes a driver available under name, drivers register themselves in init.
+func Register(name string, open OpenFunc) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	if _, ok := drivers[name]; ok {
+		panic(fmt.Sprintf("storage driver %s is registered twice", name))
+	}
+	drivers[name] = open
+}
+
+// Open creates a bucket with the driver selected by config.Driver.
+func Open(ctx context.Context, config BucketConfig) (Bucket, error) {
+	driversMu.RLock()
+	open, ok := drivers[config.Driver]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown storage driver %q, is its package imported?", config.Driver)
+	}
+
+	return open(ctx, config)
+}
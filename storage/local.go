@@ -0,0 +1,309 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("local", func(ctx context.Context, config BucketConfig) (Bucket, error) {
+		var secret []byte
+		if config.SecretEnv != "" {
+			value, ok := os.LookupEnv(config.SecretEnv)
+			if !ok || value == "" {
+				return nil, fmt.Errorf("environment variable %s isn't set", config.SecretEnv)
+			}
+			secret = []byte(value)
+		}
+
+		return NewLocalBucket(config.Dir, config.BaseURL, secret)
+	})
+}
+
+// LocalBucket keeps objects as files under a directory, content types are derived from the key
+// extension. It is meant for development and tests, Handler serves the URLs returned by SignURL.
+type LocalBucket struct {
+	dir     string
+	baseURL string
+	secret  []byte
+}
+
+// NewLocalBucket creates dir if needed. Without a secret a random one is used, so signed URLs
+// don't survive a restart.
+func NewLocalBucket(dir, baseURL string, secret []byte) (*LocalBucket, error) {
+	if dir == "" {
+		return nil, errors.New("dir is required")
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		_, err = rand.Read(secret)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &LocalBucket{
+		dir:     dir,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		secret:  secret,
+	}, nil
+}
+
+// path maps key to a file under dir, rejecting keys that would escape it.
+func (b *LocalBucket) path(key string) (string, error) {
+	if key == "" || key == "." || key == ".." || strings.HasPrefix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "../") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return filepath.Join(b.dir, filepath.FromSlash(key)), nil
+}
+
+func (b *LocalBucket) Put(ctx context.Context, key string, r io.Reader, opts ...PutOption) error {
+	name, err := b.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(name), 0o755)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first so that readers never see a partial object
+	file, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), name)
+}
+
+func (b *LocalBucket) Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error) {
+	name, err := b.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, nil, localError(err)
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	if stat.IsDir() {
+		file.Close()
+		return nil, nil, ErrNotFound
+	}
+
+	return file, localObjectInfo(key, stat), nil
+}
+
+func (b *LocalBucket) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	name, err := b.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(name)
+	if err != nil {
+		return nil, localError(err)
+	}
+	if stat.IsDir() {
+		return nil, ErrNotFound
+	}
+
+	return localObjectInfo(key, stat), nil
+}
+
+func (b *LocalBucket) List(ctx context.Context, options ListOptions) (*ListResult, error) {
+	var objects []ObjectInfo
+	err := filepath.WalkDir(b.dir, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".upload-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(b.dir, name)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, options.Prefix) || key <= options.StartAfter {
+			return nil
+		}
+
+		stat, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, *localObjectInfo(key, stat))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// WalkDir orders entries per directory, which differs from key order when names contain
+	// characters sorting before '/'
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Key < objects[j].Key
+	})
+
+	result := &ListResult{Objects: objects}
+	if maxKeys := options.MaxKeysOrDefault(); len(objects) > maxKeys {
+		result.Objects = objects[:maxKeys]
+		result.NextStartAfter = objects[maxKeys-1].Key
+	}
+
+	return result, nil
+}
+
+func (b *LocalBucket) Delete(ctx context.Context, key string) error {
+	name, err := b.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// SignURL returns base_url/key with an expiry and an HMAC of method, key and expiry, checked by Handler.
+func (b *LocalBucket) SignURL(ctx context.Context, key, method string, expires time.Duration) (string, error) {
+	err := ValidateSignMethod(method)
+	if err != nil {
+		return "", err
+	}
+	if b.baseURL == "" {
+		return "", errors.New("base_url is required to sign local storage URLs")
+	}
+	_, err = b.path(key)
+	if err != nil {
+		return "", err
+	}
+
+	expiresAt := strconv.FormatInt(time.Now().Add(SignExpiresOrDefault(expires)).Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expiresAt)
+	query.Set("signature", b.sign(method, key, expiresAt))
+
+	return b.baseURL + "/" + (&url.URL{Path: key}).EscapedPath() + "?" + query.Encode(), nil
+}
+
+func (b *LocalBucket) sign(method, key, expiresAt string) string {
+	mac := hmac.New(sha256.New, b.secret)
+	mac.Write([]byte(method + "\n" + key + "\n" + expiresAt))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Handler serves GET and PUT requests on the URLs returned by SignURL. The request path relative to
+// base_url is the key, so mount it with http.StripPrefix when base_url has a path.
+func (b *LocalBucket) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		expiresAt := r.URL.Query().Get("expires")
+
+		expected, err := hex.DecodeString(r.URL.Query().Get("signature"))
+		if err != nil {
+			http.Error(w, "invalid signature", http.StatusForbidden)
+			return
+		}
+		actual, _ := hex.DecodeString(b.sign(r.Method, key, expiresAt))
+		if !hmac.Equal(actual, expected) {
+			http.Error(w, "invalid signature", http.StatusForbidden)
+			return
+		}
+
+		unix, err := strconv.ParseInt(expiresAt, 10, 64)
+		if err != nil || time.Now().Unix() > unix {
+			http.Error(w, "url expired", http.StatusForbidden)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			file, info, err := b.Get(r.Context(), key)
+			if errors.Is(err, ErrNotFound) {
+				http.NotFound(w, r)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			defer file.Close()
+			http.ServeContent(w, r, key, info.LastModified, file.(io.ReadSeeker))
+		case http.MethodPut:
+			err = b.Put(r.Context(), key, r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+}
+
+func localObjectInfo(key string, stat fs.FileInfo) *ObjectInfo {
+	return &ObjectInfo{
+		Key:          key,
+		Size:         stat.Size(),
+		ContentType:  mime.TypeByExtension(path.Ext(key)),
+		ETag:         fmt.Sprintf(`"%x-%x"`, stat.ModTime().UnixNano(), stat.Size()),
+		LastModified: stat.ModTime(),
+	}
+}
+
+func localError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,287 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLocalBucket(t *testing.T, baseURL string) *LocalBucket {
+	t.Helper()
+
+	bucket, err := NewLocalBucket(t.TempDir(), baseURL, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bucket
+}
+
+func TestLocalBucketKeyValidation(t *testing.T) {
+	bucket := newTestLocalBucket(t, "")
+	ctx := context.Background()
+
+	invalid := []string{"", ".", "..", "../a", "a/../../b", "/a", "a//b", "a/./b", "a/"}
+	for _, key := range invalid {
+		err := bucket.Put(ctx, key, strings.NewReader("x"))
+		if !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q) = %v, want ErrInvalidKey", key, err)
+		}
+		_, err = bucket.Stat(ctx, key)
+		if !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Stat(%q) = %v, want ErrInvalidKey", key, err)
+		}
+	}
+
+	valid := []string{"a", "a/b.txt", "a/b/c", "..a", "a..b"}
+	for _, key := range valid {
+		err := bucket.Put(ctx, key+"/x", strings.NewReader("x"))
+		if err != nil {
+			t.Errorf("Put(%q) = %v", key+"/x", err)
+		}
+	}
+}
+
+func TestLocalBucketPutGetDelete(t *testing.T) {
+	bucket := newTestLocalBucket(t, "")
+	ctx := context.Background()
+
+	err := bucket.Put(ctx, "docs/a.txt", strings.NewReader("hello"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, info, err := bucket.Get(ctx, "docs/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(file)
+	file.Close()
+	if string(content) != "hello" || info.Size != 5 || !strings.HasPrefix(info.ContentType, "text/plain") {
+		t.Errorf("got %q %+v", content, info)
+	}
+
+	_, _, err = bucket.Get(ctx, "docs")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get of a directory = %v, want ErrNotFound", err)
+	}
+
+	err = bucket.Delete(ctx, "docs/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = bucket.Delete(ctx, "docs/a.txt")
+	if err != nil {
+		t.Errorf("Delete of a missing key = %v, want nil", err)
+	}
+	_, err = bucket.Stat(ctx, "docs/a.txt")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Stat after Delete = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLocalBucketListPaging(t *testing.T) {
+	bucket := newTestLocalBucket(t, "")
+	ctx := context.Background()
+
+	// a-b sorts before a/ by key although WalkDir visits the a directory first
+	keys := []string{"a/1", "a/2", "a-b", "a/3/x", "b/1", "a/4"}
+	for _, key := range keys {
+		err := bucket.Put(ctx, key, strings.NewReader(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var listed []string
+	options := ListOptions{Prefix: "a", MaxKeys: 2}
+	for page := 0; ; page++ {
+		if page > len(keys) {
+			t.Fatal("List doesn't terminate")
+		}
+
+		result, err := bucket.List(ctx, options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Objects) > options.MaxKeys {
+			t.Fatalf("page of %d objects, want at most %d", len(result.Objects), options.MaxKeys)
+		}
+		for _, object := range result.Objects {
+			listed = append(listed, object.Key)
+		}
+
+		if result.NextStartAfter == "" {
+			break
+		}
+		options.StartAfter = result.NextStartAfter
+	}
+
+	want := "a-b,a/1,a/2,a/3/x,a/4"
+	if strings.Join(listed, ",") != want {
+		t.Errorf("listed %s, want %s", strings.Join(listed, ","), want)
+	}
+}
+
+func TestLocalBucketHandler(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	bucket := newTestLocalBucket(t, server.URL+"/files")
+	mux.Handle("/files/", http.StripPrefix("/files", bucket.Handler()))
+	ctx := context.Background()
+
+	putURL, err := bucket.SignURL(ctx, "dir/hello world.txt", http.MethodPut, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, _ := http.NewRequest(http.MethodPut, putURL, strings.NewReader("hello"))
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("PUT status = %d", response.StatusCode)
+	}
+
+	getURL, err := bucket.SignURL(ctx, "dir/hello world.txt", http.MethodGet, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err = http.Get(getURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK || string(content) != "hello" {
+		t.Fatalf("GET = %d %q", response.StatusCode, content)
+	}
+
+	// a GET signature doesn't allow PUT
+	request, _ = http.NewRequest(http.MethodPut, getURL, strings.NewReader("overwrite"))
+	response, err = http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusForbidden {
+		t.Errorf("PUT with a GET signature = %d, want 403", response.StatusCode)
+	}
+
+	// the signature covers the key
+	otherURL := strings.Replace(getURL, "hello%20world.txt", "other.txt", 1)
+	response, err = http.Get(otherURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusForbidden {
+		t.Errorf("GET of another key = %d, want 403", response.StatusCode)
+	}
+
+	// and the expiry
+	tampered, _ := url.Parse(getURL)
+	query := tampered.Query()
+	query.Set("expires", "9999999999")
+	tampered.RawQuery = query.Encode()
+	response, err = http.Get(tampered.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusForbidden {
+		t.Errorf("GET with a changed expiry = %d, want 403", response.StatusCode)
+	}
+
+	expiredURL, err := bucket.SignURL(ctx, "dir/hello world.txt", http.MethodGet, -time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err = http.Get(expiredURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusForbidden {
+		t.Errorf("GET of an expired url = %d, want 403", response.StatusCode)
+	}
+
+	missingURL, err := bucket.SignURL(ctx, "missing.txt", http.MethodGet, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err = http.Get(missingURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("GET of a missing key = %d, want 404", response.StatusCode)
+	}
+}
+
+func TestLocalBucketSignURL(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := newTestLocalBucket(t, "").SignURL(ctx, "a.txt", http.MethodGet, time.Minute)
+	if err == nil {
+		t.Error("SignURL without base_url should fail")
+	}
+
+	bucket := newTestLocalBucket(t, "http://localhost/files")
+	_, err = bucket.SignURL(ctx, "a.txt", http.MethodDelete, time.Minute)
+	if !errors.Is(err, ErrUnsupported) {
+		t.Errorf("SignURL(DELETE) = %v, want ErrUnsupported", err)
+	}
+	_, err = bucket.SignURL(ctx, "../a.txt", http.MethodGet, time.Minute)
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("SignURL(../a.txt) = %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestOpenLocalSecretEnv(t *testing.T) {
+	ctx := context.Background()
+	config := BucketConfig{Driver: "local", Dir: t.TempDir(), SecretEnv: "STORAGE_TEST_SECRET"}
+
+	_, err := Open(ctx, config)
+	if err == nil {
+		t.Error("want an error while the environment variable isn't set")
+	}
+
+	t.Setenv("STORAGE_TEST_SECRET", "from-env")
+	bucket, err := Open(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bucket.(*LocalBucket).secret) != "from-env" {
+		t.Errorf("secret = %q, want the environment variable", bucket.(*LocalBucket).secret)
+	}
+}
+
+func TestLocalBucketSignURLDefaultExpires(t *testing.T) {
+	bucket := newTestLocalBucket(t, "http://localhost/files")
+
+	signed, err := bucket.SignURL(context.Background(), "a.txt", http.MethodGet, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, _ := url.Parse(signed)
+	expiresAt, err := strconv.ParseInt(parsed.Query().Get("expires"), 10, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if until := time.Until(time.Unix(expiresAt, 0)); until < DefaultSignExpires-time.Minute || until > DefaultSignExpires {
+		t.Errorf("URL expires in %s, want DefaultSignExpires", until)
+	}
+}
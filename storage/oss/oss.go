@@ -0,0 +1,204 @@
+// Package oss registers the oss storage driver for Alibaba Cloud OSS. Import it for its side effect:
+//
+//	import _ "github.com/www-xu/spark/storage/oss"
+package oss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss/credentials"
+	"github.com/www-xu/spark/storage"
+)
+
+func init() {
+	storage.Register("oss", func(ctx context.Context, config storage.BucketConfig) (storage.Bucket, error) {
+		return New(config)
+	})
+}
+
+type Bucket struct {
+	bucket string
+	client *oss.Client
+}
+
+// New creates a bucket from config. Credentials are read from access_key_env and secret_key_env when set,
+// otherwise from the SDK's ALIBABA_CLOUD_ACCESS_KEY_ID and ALIBABA_CLOUD_ACCESS_KEY_SECRET.
+func New(config storage.BucketConfig) (*Bucket, error) {
+	if config.Bucket == "" || config.Region == "" {
+		return nil, errors.New("bucket and region are required")
+	}
+
+	var provider credentials.CredentialsProvider = credentials.NewEnvironmentVariableCredentialsProvider()
+	if config.AccessKeyEnv != "" || config.SecretKeyEnv != "" {
+		accessKey, err := credential(config.AccessKeyEnv)
+		if err != nil {
+			return nil, err
+		}
+		secretKey, err := credential(config.SecretKeyEnv)
+		if err != nil {
+			return nil, err
+		}
+		provider = credentials.NewStaticCredentialsProvider(accessKey, secretKey)
+	}
+
+	cfg := oss.LoadDefaultConfig().
+		WithRegion(config.Region).
+		WithCredentialsProvider(provider)
+	if config.Endpoint != "" {
+		cfg = cfg.WithEndpoint(config.Endpoint)
+	}
+
+	return &Bucket{
+		bucket: config.Bucket,
+		client: oss.NewClient(cfg),
+	}, nil
+}
+
+// Client returns the underlying SDK client.
+func (b *Bucket) Client() *oss.Client {
+	return b.client
+}
+
+func (b *Bucket) Put(ctx context.Context, key string, r io.Reader, opts ...storage.PutOption) error {
+	options := storage.NewPutOptions(opts)
+
+	request := &oss.PutObjectRequest{
+		Bucket: oss.Ptr(b.bucket),
+		Key:    oss.Ptr(key),
+	}
+	if options.ContentType != "" {
+		request.ContentType = oss.Ptr(options.ContentType)
+	}
+
+	// the uploader switches to a multipart upload for bodies larger than a part
+	_, err := b.client.NewUploader().UploadFrom(ctx, request, r)
+	if err != nil {
+		return fmt.Errorf("put oss object %s/%s: %w", b.bucket, key, err)
+	}
+
+	return nil
+}
+
+func (b *Bucket) Get(ctx context.Context, key string) (io.ReadCloser, *storage.ObjectInfo, error) {
+	result, err := b.client.GetObject(ctx, &oss.GetObjectRequest{
+		Bucket: oss.Ptr(b.bucket),
+		Key:    oss.Ptr(key),
+	})
+	if err != nil {
+		return nil, nil, convertError(err)
+	}
+
+	return result.Body, objectInfo(key, result.ContentLength, result.ContentType, result.ETag, result.LastModified), nil
+}
+
+func (b *Bucket) Stat(ctx context.Context, key string) (*storage.ObjectInfo, error) {
+	result, err := b.client.HeadObject(ctx, &oss.HeadObjectRequest{
+		Bucket: oss.Ptr(b.bucket),
+		Key:    oss.Ptr(key),
+	})
+	if err != nil {
+		return nil, convertError(err)
+	}
+
+	return objectInfo(key, result.ContentLength, result.ContentType, result.ETag, result.LastModified), nil
+}
+
+func (b *Bucket) List(ctx context.Context, options storage.ListOptions) (*storage.ListResult, error) {
+	request := &oss.ListObjectsV2Request{
+		Bucket:  oss.Ptr(b.bucket),
+		MaxKeys: int32(options.MaxKeysOrDefault()),
+	}
+	if options.Prefix != "" {
+		request.Prefix = oss.Ptr(options.Prefix)
+	}
+	if options.StartAfter != "" {
+		request.StartAfter = oss.Ptr(options.StartAfter)
+	}
+
+	result, err := b.client.ListObjectsV2(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &storage.ListResult{Objects: make([]storage.ObjectInfo, 0, len(result.Contents))}
+	for _, object := range result.Contents {
+		list.Objects = append(list.Objects, *objectInfo(oss.ToString(object.Key), object.Size, nil, object.ETag, object.LastModified))
+	}
+	if result.IsTruncated && len(list.Objects) > 0 {
+		list.NextStartAfter = list.Objects[len(list.Objects)-1].Key
+	}
+
+	return list, nil
+}
+
+func (b *Bucket) Delete(ctx context.Context, key string) error {
+	_, err := b.client.DeleteObject(ctx, &oss.DeleteObjectRequest{
+		Bucket: oss.Ptr(b.bucket),
+		Key:    oss.Ptr(key),
+	})
+	if err != nil && !errors.Is(convertError(err), storage.ErrNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+func (b *Bucket) SignURL(ctx context.Context, key, method string, expires time.Duration) (string, error) {
+	err := storage.ValidateSignMethod(method)
+	if err != nil {
+		return "", err
+	}
+
+	var request any = &oss.GetObjectRequest{Bucket: oss.Ptr(b.bucket), Key: oss.Ptr(key)}
+	if method == http.MethodPut {
+		request = &oss.PutObjectRequest{Bucket: oss.Ptr(b.bucket), Key: oss.Ptr(key)}
+	}
+
+	result, err := b.client.Presign(ctx, request, oss.PresignExpires(storage.SignExpiresOrDefault(expires)))
+	if err != nil {
+		return "", err
+	}
+
+	return result.URL, nil
+}
+
+func objectInfo(key string, size int64, contentType, etag *string, lastModified *time.Time) *storage.ObjectInfo {
+	info := &storage.ObjectInfo{
+		Key:         key,
+		Size:        size,
+		ContentType: oss.ToString(contentType),
+		ETag:        oss.ToString(etag),
+	}
+	if lastModified != nil {
+		info.LastModified = *lastModified
+	}
+	return info
+}
+
+func convertError(err error) error {
+	var serviceError *oss.ServiceError
+	if errors.As(err, &serviceError) && serviceError.StatusCode == http.StatusNotFound && serviceError.Code != "NoSuchBucket" {
+		return storage.ErrNotFound
+	}
+	return err
+}
+
+func credential(env string) (string, error) {
+	if env == "" {
+		return "", errors.New("access_key_env and secret_key_env must be set together")
+	}
+
+	value, ok := os.LookupEnv(env)
+	if !ok || value == "" {
+		return "", fmt.Errorf("environment variable %s isn't set", env)
+	}
+
+	return value, nil
+}
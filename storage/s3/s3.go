@@ -0,0 +1,204 @@
+// Package s3 registers the s3 storage driver, which works with AWS S3 and S3-compatible endpoints
+// such as MinIO. Import it for its side effect:
+//
+//	import _ "github.com/www-xu/spark/storage/s3"
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/www-xu/spark/storage"
+)
+
+const (
+	defaultAccessKeyEnv = "AWS_ACCESS_KEY_ID"
+	defaultSecretKeyEnv = "AWS_SECRET_ACCESS_KEY"
+)
+
+func init() {
+	storage.Register("s3", func(ctx context.Context, config storage.BucketConfig) (storage.Bucket, error) {
+		return New(config)
+	})
+}
+
+type Bucket struct {
+	bucket string
+	client *minio.Client
+}
+
+// New creates a bucket from config, reading the credentials from access_key_env and secret_key_env.
+// The client keeps minio's own transport, which already retries failed requests.
+func New(config storage.BucketConfig) (*Bucket, error) {
+	if config.Endpoint == "" || config.Bucket == "" {
+		return nil, errors.New("endpoint and bucket are required")
+	}
+
+	accessKey, err := credential(config.AccessKeyEnv, defaultAccessKeyEnv)
+	if err != nil {
+		return nil, err
+	}
+	secretKey, err := credential(config.SecretKeyEnv, defaultSecretKeyEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	bucketLookup := minio.BucketLookupAuto
+	if config.PathStyle {
+		bucketLookup = minio.BucketLookupPath
+	}
+
+	client, err := minio.New(config.Endpoint, &minio.Options{
+		Creds:        credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure:       !config.Insecure,
+		Region:       config.Region,
+		BucketLookup: bucketLookup,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Bucket{
+		bucket: config.Bucket,
+		client: client,
+	}, nil
+}
+
+// Client returns the underlying minio client.
+func (b *Bucket) Client() *minio.Client {
+	return b.client
+}
+
+func (b *Bucket) Put(ctx context.Context, key string, r io.Reader, opts ...storage.PutOption) error {
+	options := storage.NewPutOptions(opts)
+
+	_, err := b.client.PutObject(ctx, b.bucket, key, r, options.Size, minio.PutObjectOptions{
+		ContentType: options.ContentType,
+	})
+	if err != nil {
+		return fmt.Errorf("put s3 object %s/%s: %w", b.bucket, key, err)
+	}
+
+	return nil
+}
+
+func (b *Bucket) Get(ctx context.Context, key string) (io.ReadCloser, *storage.ObjectInfo, error) {
+	object, err := b.client.GetObject(ctx, b.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, nil, convertError(err)
+	}
+
+	// GetObject is lazy, Stat sends the request and reports missing keys
+	info, err := object.Stat()
+	if err != nil {
+		object.Close()
+		return nil, nil, convertError(err)
+	}
+
+	return object, objectInfo(info), nil
+}
+
+func (b *Bucket) Stat(ctx context.Context, key string) (*storage.ObjectInfo, error) {
+	info, err := b.client.StatObject(ctx, b.bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, convertError(err)
+	}
+
+	return objectInfo(info), nil
+}
+
+func (b *Bucket) List(ctx context.Context, options storage.ListOptions) (*storage.ListResult, error) {
+	// cancelling stops the listing goroutine once the page is full
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	maxKeys := options.MaxKeysOrDefault()
+	result := &storage.ListResult{}
+	for info := range b.client.ListObjects(ctx, b.bucket, minio.ListObjectsOptions{
+		Prefix:     options.Prefix,
+		StartAfter: options.StartAfter,
+		Recursive:  true,
+		MaxKeys:    maxKeys,
+	}) {
+		if info.Err != nil {
+			return nil, convertError(info.Err)
+		}
+		if len(result.Objects) == maxKeys {
+			result.NextStartAfter = result.Objects[maxKeys-1].Key
+			break
+		}
+		result.Objects = append(result.Objects, *objectInfo(info))
+	}
+
+	return result, nil
+}
+
+func (b *Bucket) Delete(ctx context.Context, key string) error {
+	err := b.client.RemoveObject(ctx, b.bucket, key, minio.RemoveObjectOptions{})
+	if err != nil && !errors.Is(convertError(err), storage.ErrNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+func (b *Bucket) SignURL(ctx context.Context, key, method string, expires time.Duration) (string, error) {
+	err := storage.ValidateSignMethod(method)
+	if err != nil {
+		return "", err
+	}
+
+	expires = storage.SignExpiresOrDefault(expires)
+	var signed *url.URL
+	if method == http.MethodPut {
+		signed, err = b.client.PresignedPutObject(ctx, b.bucket, key, expires)
+	} else {
+		signed, err = b.client.PresignedGetObject(ctx, b.bucket, key, expires, nil)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return signed.String(), nil
+}
+
+func objectInfo(info minio.ObjectInfo) *storage.ObjectInfo {
+	return &storage.ObjectInfo{
+		Key:          info.Key,
+		Size:         info.Size,
+		ContentType:  info.ContentType,
+		ETag:         info.ETag,
+		LastModified: info.LastModified,
+	}
+}
+
+func convertError(err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return storage.ErrNotFound
+	}
+	return err
+}
+
+// credential reads a credential from the configured environment variable, which must be set.
+// Without one it falls back to defaultEnv, which may be unset for anonymous access to public buckets.
+func credential(env, defaultEnv string) (string, error) {
+	if env == "" {
+		value := os.Getenv(defaultEnv)
+		return value, nil
+	}
+
+	value, ok := os.LookupEnv(env)
+	if !ok || value == "" {
+		return "", fmt.Errorf("environment variable %s isn't set", env)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,285 @@
+package s3
+
+import (
+	"bufio"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/www-xu/spark/storage"
+)
+
+// fakeS3 keeps the objects of bucket media in memory and answers the S3 calls the driver makes.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]fakeObject
+}
+
+type fakeObject struct {
+	body        []byte
+	contentType string
+}
+
+var lastModified = time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=test-access/") {
+		writeError(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+
+	key, ok := strings.CutPrefix(r.URL.Path, "/media/")
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	if key == "" && r.Method == http.MethodGet {
+		s.list(w, r.URL.Query())
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		body, err := readBody(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		s.objects[key] = fakeObject{body: body, contentType: r.Header.Get("Content-Type")}
+		w.Header().Set("ETag", `"etag-`+key+`"`)
+	case http.MethodGet, http.MethodHead:
+		object, ok := s.objects[key]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("Content-Type", object.contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(object.body)))
+		w.Header().Set("ETag", `"etag-`+key+`"`)
+		w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(object.body)
+		}
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed")
+	}
+}
+
+// readBody reads the body of an upload, which minio signs in chunks when it isn't sent over https.
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Header.Get("X-Amz-Content-Sha256") != "STREAMING-AWS4-HMAC-SHA256-PAYLOAD" {
+		return io.ReadAll(r.Body)
+	}
+
+	// each chunk is <hex size>;chunk-signature=<signature>\r\n<data>\r\n, the last one is empty
+	var body []byte
+	reader := bufio.NewReader(r.Body)
+	for {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.ParseInt(strings.SplitN(header, ";", 2)[0], 16, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		chunk := make([]byte, size+2)
+		_, err = io.ReadFull(reader, chunk)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return body, nil
+		}
+		body = append(body, chunk[:size]...)
+	}
+}
+
+func (s *fakeS3) list(w http.ResponseWriter, query url.Values) {
+	keys := make([]string, 0, len(s.objects))
+	for key := range s.objects {
+		after := query.Get("start-after")
+		if token := query.Get("continuation-token"); token != "" {
+			after = token
+		}
+		if strings.HasPrefix(key, query.Get("prefix")) && key > after {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	maxKeys, _ := strconv.Atoi(query.Get("max-keys"))
+	var result strings.Builder
+	result.WriteString(`<ListBucketResult><Name>media</Name>`)
+	if maxKeys > 0 && len(keys) > maxKeys {
+		keys = keys[:maxKeys]
+		fmt.Fprintf(&result, `<IsTruncated>true</IsTruncated><NextContinuationToken>%s</NextContinuationToken>`, keys[maxKeys-1])
+	}
+	for _, key := range keys {
+		fmt.Fprintf(&result, `<Contents><Key>%s</Key><Size>%d</Size><ETag>"etag-%s"</ETag><LastModified>%s</LastModified></Contents>`,
+			key, len(s.objects[key].body), key, lastModified.Format(time.RFC3339))
+	}
+	result.WriteString(`</ListBucketResult>`)
+
+	w.Header().Set("Content-Type", "application/xml")
+	_, _ = io.WriteString(w, result.String())
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_ = xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string   `xml:"Code"`
+	}{Code: code})
+}
+
+func newTestBucket(t *testing.T) *Bucket {
+	t.Helper()
+
+	server := httptest.NewServer(&fakeS3{objects: map[string]fakeObject{}})
+	t.Cleanup(server.Close)
+
+	t.Setenv("S3_TEST_ACCESS_KEY", "test-access")
+	t.Setenv("S3_TEST_SECRET_KEY", "test-secret")
+	bucket, err := New(storage.BucketConfig{
+		Bucket:       "media",
+		Endpoint:     strings.TrimPrefix(server.URL, "http://"),
+		Region:       "us-east-1",
+		AccessKeyEnv: "S3_TEST_ACCESS_KEY",
+		SecretKeyEnv: "S3_TEST_SECRET_KEY",
+		Insecure:     true,
+		PathStyle:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bucket
+}
+
+func TestBucket(t *testing.T) {
+	bucket := newTestBucket(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"docs/a.txt", "docs/b.txt", "docs/c.txt", "images/d.png"} {
+		err := bucket.Put(ctx, key, strings.NewReader("hello "+key), storage.WithContentType("text/plain"), storage.WithSize(int64(len("hello "+key))))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	body, info, err := bucket.Get(ctx, "docs/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(body)
+	_ = body.Close()
+	if string(content) != "hello docs/a.txt" {
+		t.Errorf("content = %q", content)
+	}
+	if info.Key != "docs/a.txt" || info.Size != 16 || info.ContentType != "text/plain" || info.ETag != "etag-docs/a.txt" || !info.LastModified.Equal(lastModified) {
+		t.Errorf("unexpected info %+v", info)
+	}
+
+	info, err = bucket.Stat(ctx, "docs/b.txt")
+	if err != nil || info.Size != 16 || info.ContentType != "text/plain" {
+		t.Errorf("Stat = %+v, %v", info, err)
+	}
+
+	_, _, err = bucket.Get(ctx, "docs/missing.txt")
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Get(missing) = %v, want ErrNotFound", err)
+	}
+	_, err = bucket.Stat(ctx, "docs/missing.txt")
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Stat(missing) = %v, want ErrNotFound", err)
+	}
+
+	page, err := bucket.List(ctx, storage.ListOptions{Prefix: "docs/", MaxKeys: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Objects) != 2 || page.Objects[0].Key != "docs/a.txt" || page.NextStartAfter != "docs/b.txt" {
+		t.Fatalf("first page = %+v", page)
+	}
+	page, err = bucket.List(ctx, storage.ListOptions{Prefix: "docs/", MaxKeys: 2, StartAfter: page.NextStartAfter})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Objects) != 1 || page.Objects[0].Key != "docs/c.txt" || page.NextStartAfter != "" {
+		t.Errorf("last page = %+v", page)
+	}
+
+	err = bucket.Delete(ctx, "docs/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = bucket.Stat(ctx, "docs/a.txt")
+	if !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Stat after Delete = %v, want ErrNotFound", err)
+	}
+	err = bucket.Delete(ctx, "docs/a.txt")
+	if err != nil {
+		t.Errorf("deleting a missing key: %v", err)
+	}
+}
+
+func TestSignURL(t *testing.T) {
+	bucket := newTestBucket(t)
+	ctx := context.Background()
+
+	signed, err := bucket.SignURL(ctx, "docs/a.txt", http.MethodGet, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _ := url.Parse(signed)
+	if parsed.Path != "/media/docs/a.txt" || parsed.Query().Get("X-Amz-Expires") != "900" || parsed.Query().Get("X-Amz-Signature") == "" {
+		t.Errorf("signed URL = %s, want DefaultSignExpires", signed)
+	}
+
+	signed, err = bucket.SignURL(ctx, "docs/a.txt", http.MethodPut, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, _ = url.Parse(signed)
+	if parsed.Query().Get("X-Amz-Expires") != "60" {
+		t.Errorf("signed URL = %s, want 60s", signed)
+	}
+
+	_, err = bucket.SignURL(ctx, "docs/a.txt", http.MethodDelete, time.Minute)
+	if !errors.Is(err, storage.ErrUnsupported) {
+		t.Errorf("SignURL(DELETE) = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(storage.BucketConfig{Bucket: "media"})
+	if err == nil {
+		t.Error("want an error without an endpoint")
+	}
+
+	_, err = New(storage.BucketConfig{Bucket: "media", Endpoint: "localhost:9000", AccessKeyEnv: "S3_TEST_UNSET"})
+	if err == nil {
+		t.Error("want an error while access_key_env isn't set")
+	}
+}
@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrNotFound    = errors.New("storage object not found")
+	ErrInvalidKey  = errors.New("storage key is invalid")
+	ErrUnsupported = errors.New("storage operation isn't supported by the driver")
+)
+
+// Bucket is a provider-neutral view of an object storage bucket. Keys are slash separated paths
+// without a leading slash.
+type Bucket interface {
+	// Put stores r under key, replacing the existing object.
+	Put(ctx context.Context, key string, r io.Reader, opts ...PutOption) error
+	// Get returns the content of the object, callers must close it. It returns ErrNotFound for missing keys.
+	Get(ctx context.Context, key string) (io.ReadCloser, *ObjectInfo, error)
+	// Stat returns the attributes of the object or ErrNotFound.
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	// List returns a page of the objects whose key starts with options.Prefix, ordered by key.
+	List(ctx context.Context, options ListOptions) (*ListResult, error)
+	// Delete removes the object, deleting a missing key isn't an error.
+	Delete(ctx context.Context, key string) error
+	// SignURL returns a URL that allows method (GET or PUT) on key without credentials until it expires,
+	// 0 means DefaultSignExpires.
+	SignURL(ctx context.Context, key, method string, expires time.Duration) (string, error)
+}
+
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+type ListOptions struct {
+	Prefix     string
+	StartAfter string // returns keys after this one, pass ListResult.NextStartAfter to read the next page
+	MaxKeys    int    // defaults to 1000
+}
+
+type ListResult struct {
+	Objects        []ObjectInfo
+	NextStartAfter string // empty on the last page
+}
+
+type PutOptions struct {
+	ContentType string
+	Size        int64 // -1 when unknown, lets drivers choose between a single and a multipart upload
+}
+
+type PutOption func(*PutOptions)
+
+func WithContentType(contentType string) PutOption {
+	return func(o *PutOptions) {
+		o.ContentType = contentType
+	}
+}
+
+// WithSize tells the driver the length of the body.
+func WithSize(size int64) PutOption {
+	return func(o *PutOptions) {
+		o.Size = size
+	}
+}
+
+// NewPutOptions applies opts, it is meant for drivers.
+func NewPutOptions(opts []PutOption) PutOptions {
+	options := PutOptions{Size: -1}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+const defaultMaxKeys = 1000
+
+// MaxKeysOrDefault returns options.MaxKeys or its default, it is meant for drivers.
+func (options ListOptions) MaxKeysOrDefault() int {
+	if options.MaxKeys <= 0 {
+		return defaultMaxKeys
+	}
+	return options.MaxKeys
+}
+
+// DefaultSignExpires is how long signed URLs are valid when SignURL is called with 0.
+const DefaultSignExpires = 15 * time.Minute
+
+// SignExpiresOrDefault returns expires or DefaultSignExpires when it is 0, it is meant for drivers.
+func SignExpiresOrDefault(expires time.Duration) time.Duration {
+	if expires == 0 {
+		return DefaultSignExpires
+	}
+	return expires
+}
+
+// ValidateSignMethod checks that method can be signed, it is meant for drivers.
+func ValidateSignMethod(method string) error {
+	if method != http.MethodGet && method != http.MethodPut {
+		return fmt.Errorf("%w: signing %s", ErrUnsupported, method)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+)
+
+const (
+	DedupeMemory = "memory"
+	DedupeRedis  = "redis"
+
+	defaultDedupeSize = 10000
+)
+
+// State is what Claim found for a delivery.
+type State int
+
+const (
+	// Claimed means the caller handles the delivery and completes or releases it afterwards.
+	Claimed State = iota
+	// InProgress means another delivery of the same event is being handled.
+	InProgress
+	// Done means the event was handled before.
+	Done
+)
+
+// Deduper tracks deliveries by key. Claim marks a new delivery in progress for lease, Complete
+// remembers it as handled for the dedupe ttl and Release forgets it so that a retry goes through.
+type Deduper interface {
+	Claim(ctx context.Context, key string, lease time.Duration) (State, error)
+	Complete(ctx context.Context, key string) error
+	Release(ctx context.Context, key string)
+}
+
+var dedupers = map[string]func(ttl time.Duration) Deduper{}
+
+// RegisterDeduper makes a dedupe store available to webhook.dedupe under name, it must be called before spark.Init.
+func RegisterDeduper(name string, newDeduper func(ttl time.Duration) Deduper) {
+	dedupers[name] = newDeduper
+}
+
+type memoryEntry struct {
+	done  bool
+	until time.Time // end of the lease while not done
+}
+
+type memoryDeduper struct {
+	mu    sync.Mutex
+	cache *expirable.LRU[string, memoryEntry]
+}
+
+func newMemoryDeduper(ttl time.Duration) *memoryDeduper {
+	return &memoryDeduper{cache: expirable.NewLRU[string, memoryEntry](defaultDedupeSize, nil, ttl)}
+}
+
+func (d *memoryDeduper) Claim(ctx context.Context, key string, lease time.Duration) (State, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if entry, ok := d.cache.Get(key); ok {
+		if entry.done {
+			return Done, nil
+		}
+		if time.Now().Before(entry.until) {
+			return InProgress, nil
+		}
+	}
+	d.cache.Add(key, memoryEntry{until: time.Now().Add(lease)})
+
+	return Claimed, nil
+}
+
+func (d *memoryDeduper) Complete(ctx context.Context, key string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.cache.Add(key, memoryEntry{done: true})
+	return nil
+}
+
+func (d *memoryDeduper) Release(ctx context.Context, key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if entry, ok := d.cache.Peek(key); ok && !entry.done {
+		d.cache.Remove(key)
+	}
+}
@@ -0,0 +1,42 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryDeduper(t *testing.T) {
+	ctx := context.Background()
+	d := newMemoryDeduper(time.Hour)
+
+	expect := func(key string, lease time.Duration, want State) {
+		t.Helper()
+
+		state, err := d.Claim(ctx, key, lease)
+		if err != nil || state != want {
+			t.Fatalf("Claim(%s) = %v, %v, want %v", key, state, err, want)
+		}
+	}
+
+	expect("a", time.Hour, Claimed)
+	expect("a", time.Hour, InProgress)
+
+	d.Release(ctx, "a")
+	expect("a", time.Hour, Claimed)
+
+	err := d.Complete(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect("a", time.Hour, Done)
+
+	// a completed delivery isn't forgotten by a late release
+	d.Release(ctx, "a")
+	expect("a", time.Hour, Done)
+
+	// the claim of a crashed delivery expires with its lease
+	expect("b", time.Millisecond, Claimed)
+	time.Sleep(5 * time.Millisecond)
+	expect("b", time.Hour, Claimed)
+}
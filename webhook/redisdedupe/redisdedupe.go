@@ -0,0 +1,63 @@
+// Package redisdedupe registers the redis dedupe store of webhook receivers, shared by every replica
+// through the redis component. Import it for its side effect:
+//
+//	import _ "github.com/www-xu/spark/webhook/redisdedupe"
+package redisdedupe
+
+import (
+	"context"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/www-xu/spark/redis"
+	"github.com/www-xu/spark/webhook"
+)
+
+const (
+	pending = "pending"
+	done    = "done"
+)
+
+// claimScript returns the webhook.State of the key, claiming it when it's free.
+var claimScript = goredis.NewScript(`
+if redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2], 'NX') then
+	return 0
+end
+if redis.call('GET', KEYS[1]) == ARGV[3] then
+	return 2
+end
+return 1
+`)
+
+// releaseScript deletes the key unless the delivery was completed meanwhile.
+var releaseScript = goredis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+func init() {
+	webhook.RegisterDeduper(webhook.DedupeRedis, func(ttl time.Duration) webhook.Deduper {
+		return &deduper{ttl: ttl, client: redis.Get}
+	})
+}
+
+type deduper struct {
+	ttl    time.Duration
+	client func(ctx context.Context) *goredis.Client
+}
+
+func (d *deduper) Claim(ctx context.Context, key string, lease time.Duration) (webhook.State, error) {
+	state, err := claimScript.Run(ctx, d.client(ctx), []string{key}, pending, lease.Milliseconds(), done).Int()
+	return webhook.State(state), err
+}
+
+func (d *deduper) Complete(ctx context.Context, key string) error {
+	return d.client(ctx).Set(ctx, key, done, d.ttl).Err()
+}
+
+func (d *deduper) Release(ctx context.Context, key string) {
+	ctx = context.WithoutCancel(ctx)
+	_ = releaseScript.Run(ctx, d.client(ctx), []string{key}, pending).Err()
+}
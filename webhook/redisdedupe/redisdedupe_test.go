@@ -0,0 +1,51 @@
+package redisdedupe
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/www-xu/spark/webhook"
+)
+
+func TestDeduper(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	ctx := context.Background()
+	d := &deduper{ttl: time.Hour, client: func(ctx context.Context) *goredis.Client { return client }}
+
+	expect := func(key string, lease time.Duration, want webhook.State) {
+		t.Helper()
+
+		state, err := d.Claim(ctx, key, lease)
+		if err != nil || state != want {
+			t.Fatalf("Claim(%s) = %v, %v, want %v", key, state, err, want)
+		}
+	}
+
+	expect("a", time.Hour, webhook.Claimed)
+	expect("a", time.Hour, webhook.InProgress)
+	if ttl := server.TTL("a"); ttl != time.Hour {
+		t.Errorf("lease ttl = %s, want 1h", ttl)
+	}
+
+	d.Release(ctx, "a")
+	expect("a", time.Hour, webhook.Claimed)
+
+	err := d.Complete(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect("a", time.Minute, webhook.Done)
+
+	d.Release(ctx, "a")
+	expect("a", time.Minute, webhook.Done)
+
+	expect("b", time.Second, webhook.Claimed)
+	server.FastForward(2 * time.Second)
+	expect("b", time.Hour, webhook.Claimed)
+}
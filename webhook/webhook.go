@@ -0,0 +1,277 @@
+// Package webhook receives signed JSON callbacks, e.g. from n8n flows or dify workflows. A Receiver
+// verifies the HMAC-SHA256 signature of the body, handles every delivery once across retries and
+// dispatches it by its event field to the handlers registered with On.
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/www-xu/spark/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultDedupeTTL    = 24 * time.Hour
+	defaultDedupeLease  = 5 * time.Minute
+	defaultMaxBodyBytes = 1 << 20
+)
+
+var tracer = otel.Tracer("webhook")
+
+// ErrInvalidEvent is wrapped by handler errors about malformed callback data, which respond 400 instead of 500.
+var ErrInvalidEvent = errors.New("invalid callback data")
+
+type Config struct {
+	Secret      string        `mapstructure:"secret"`       // shared secret of the HMAC-SHA256 signature
+	SecretEnv   string        `mapstructure:"secret_env"`   // name of the environment variable holding the secret, preferred over secret
+	Dedupe      string        `mapstructure:"dedupe"`       // memory or redis (import webhook/redisdedupe), redis is needed when several replicas receive callbacks
+	DedupeTTL   time.Duration `mapstructure:"dedupe_ttl"`   // how long a handled delivery is remembered
+	DedupeLease time.Duration `mapstructure:"dedupe_lease"` // how long a delivery being handled holds off its retries, defaults to 5 minutes
+}
+
+// Options describe the callbacks of one sender.
+type Options struct {
+	Name            string   // name of the sender, used in span names, attributes and errors
+	SignatureHeader string   // header with the hex digest of the body, optionally prefixed with sha256=
+	IDField         string   // JSON field identifying a delivery, events with the same type and id are handled once
+	KeyPrefix       string   // prefix of the dedupe keys
+	SpanFields      []string // further JSON string fields recorded as span attributes
+}
+
+// Handler handles the raw body of one event type. Returning an error responds 500 so that the sender
+// may retry, or 400 when it wraps ErrInvalidEvent.
+type Handler func(ctx context.Context, body []byte) error
+
+type Receiver struct {
+	options  Options
+	mu       sync.RWMutex
+	handlers map[string]Handler
+	secret   []byte
+	dedupe   Deduper
+	lease    time.Duration
+}
+
+func NewReceiver(options Options) *Receiver {
+	return &Receiver{
+		options:  options,
+		handlers: map[string]Handler{},
+	}
+}
+
+// Configure resolves the secret and the dedupe store, the owning component calls it when it's instantiated.
+func (r *Receiver) Configure(config Config) error {
+	secret := config.Secret
+	if config.SecretEnv != "" {
+		var ok bool
+		secret, ok = os.LookupEnv(config.SecretEnv)
+		if !ok || secret == "" {
+			return fmt.Errorf("environment variable %s isn't set", config.SecretEnv)
+		}
+	}
+	r.secret = []byte(secret)
+
+	ttl := config.DedupeTTL
+	if ttl <= 0 {
+		ttl = defaultDedupeTTL
+	}
+	r.lease = config.DedupeLease
+	if r.lease <= 0 {
+		r.lease = defaultDedupeLease
+	}
+
+	if config.Dedupe == "" || config.Dedupe == DedupeMemory {
+		r.dedupe = newMemoryDeduper(ttl)
+		return nil
+	}
+
+	newDeduper, ok := dedupers[config.Dedupe]
+	if !ok {
+		if config.Dedupe == DedupeRedis {
+			return fmt.Errorf("%s webhook dedupe redis needs github.com/www-xu/spark/webhook/redisdedupe to be imported", r.options.Name)
+		}
+		return fmt.Errorf("unknown %s webhook dedupe store: %s", r.options.Name, config.Dedupe)
+	}
+	r.dedupe = newDeduper(ttl)
+
+	return nil
+}
+
+// On registers the handler of an event type.
+func (r *Receiver) On(event string, handler Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.handlers[event] = handler
+}
+
+// Decode unmarshals callback data into v, errors wrap ErrInvalidEvent.
+func Decode(data []byte, v any) error {
+	err := json.Unmarshal(data, v)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidEvent, err)
+	}
+	return nil
+}
+
+// GinHandler returns a gin handler that verifies the signature of callbacks, drops deliveries already
+// handled, answers 409 to retries of a delivery still being handled and dispatches the rest.
+func (r *Receiver) GinHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, defaultMaxBodyBytes))
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read body"})
+			return
+		}
+
+		if !r.verify(body, ctx.GetHeader(r.options.SignatureHeader)) {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid signature"})
+			return
+		}
+
+		var fields map[string]json.RawMessage
+		err = json.Unmarshal(body, &fields)
+		event, id := stringField(fields, "event"), stringField(fields, r.options.IDField)
+		if err != nil || event == "" || id == "" {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "event and " + r.options.IDField + " are required"})
+			return
+		}
+
+		status, err := r.dispatch(ctx.Request.Context(), ctx.Request.Header, event, id, fields, body)
+		if err != nil {
+			ctx.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+			return
+		}
+
+		ctx.Status(status)
+	}
+}
+
+func stringField(fields map[string]json.RawMessage, name string) string {
+	var value string
+	_ = json.Unmarshal(fields[name], &value)
+	return value
+}
+
+func (r *Receiver) verify(body []byte, signature string) bool {
+	// without a secret every callback is rejected
+	if len(r.secret) == 0 {
+		return false
+	}
+
+	expected, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, r.secret)
+	mac.Write(body)
+
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+func (r *Receiver) dispatch(ctx context.Context, header http.Header, event, id string, fields map[string]json.RawMessage, body []byte) (status int, err error) {
+	name := r.options.Name
+	attributes := []attribute.KeyValue{
+		attribute.String(name+".event", event),
+		attribute.String(name+"."+r.options.IDField, id),
+	}
+	for _, field := range r.options.SpanFields {
+		if value := stringField(fields, field); value != "" {
+			attributes = append(attributes, attribute.String(name+"."+field, value))
+		}
+	}
+
+	ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+	ctx, span := tracer.Start(ctx, name+".webhook "+event,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attributes...),
+	)
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	spanCtx := trace.SpanContextFromContext(ctx)
+	ctx = context.WithValue(ctx, log.TraceIdKey, spanCtx.TraceID().String())
+	ctx = context.WithValue(ctx, log.SpanIdKey, spanCtx.SpanID().String())
+
+	r.mu.RLock()
+	handler, ok := r.handlers[event]
+	r.mu.RUnlock()
+	if !ok {
+		return http.StatusNotFound, fmt.Errorf("no handler for %s event %s", name, event)
+	}
+
+	key := r.options.KeyPrefix + event + ":" + id
+	state, err := r.dedupe.Claim(ctx, key, r.lease)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	switch state {
+	case Done:
+		span.SetAttributes(attribute.Bool(name+".duplicate", true))
+		return http.StatusOK, nil
+	case InProgress:
+		// the first delivery may still fail, so the retry must not be acknowledged yet
+		span.SetAttributes(attribute.Bool(name+".duplicate", true))
+		return http.StatusConflict, fmt.Errorf("%s event %s %s is being handled", name, event, id)
+	}
+
+	err = r.handle(ctx, handler, key, body)
+	if err != nil {
+		log.WithContext(ctx).WithError(err).WithFields(map[string]interface{}{
+			"event":           event,
+			r.options.IDField: id,
+		}).Error("failed to handle " + name + " callback")
+
+		if errors.Is(err, ErrInvalidEvent) {
+			return http.StatusBadRequest, err
+		}
+		return http.StatusInternalServerError, err
+	}
+
+	err = r.dedupe.Complete(context.WithoutCancel(ctx), key)
+	if err != nil {
+		// retries are held off until the lease expires and then handled again
+		log.WithContext(ctx).WithError(err).WithField("key", key).Warn("failed to complete webhook delivery")
+	}
+
+	return http.StatusOK, nil
+}
+
+// handle runs the handler of a claimed delivery and releases the claim when it fails or panics,
+// so that a retry of the callback goes through.
+func (r *Receiver) handle(ctx context.Context, handler Handler, key string, body []byte) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			r.dedupe.Release(ctx, key)
+			panic(p)
+		}
+	}()
+
+	err = handler(ctx, body)
+	if err != nil {
+		r.dedupe.Release(ctx, key)
+	}
+	return err
+}
@@ -0,0 +1,229 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testSecret = "s3cret"
+
+func newTestReceiver(t *testing.T) (*Receiver, *gin.Engine) {
+	t.Helper()
+
+	receiver := NewReceiver(Options{
+		Name:            "test",
+		SignatureHeader: "X-Test-Signature",
+		IDField:         "run_id",
+		KeyPrefix:       "test:webhook:",
+	})
+	err := receiver.Configure(Config{Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/callback", receiver.GinHandler())
+
+	return receiver, engine
+}
+
+func sign(body string) string {
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func post(engine *gin.Engine, body, signature string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(body))
+	request.Header.Set("X-Test-Signature", signature)
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestSignatureVerification(t *testing.T) {
+	receiver, engine := newTestReceiver(t)
+	var calls atomic.Int32
+	receiver.On("finished", func(ctx context.Context, body []byte) error {
+		calls.Add(1)
+		return nil
+	})
+
+	body := `{"event":"finished","run_id":"r1"}`
+	tests := []struct {
+		name      string
+		body      string
+		signature string
+		status    int
+	}{
+		{"missing", body, "", http.StatusUnauthorized},
+		{"not hex", body, "xyz", http.StatusUnauthorized},
+		{"other secret", body, hex.EncodeToString(hmac.New(sha256.New, []byte("other")).Sum(nil)), http.StatusUnauthorized},
+		{"tampered body", `{"event":"finished","run_id":"r2"}`, sign(body), http.StatusUnauthorized},
+		{"valid", body, sign(body), http.StatusOK},
+		{"valid with prefix", `{"event":"finished","run_id":"r3"}`, "sha256=" + sign(`{"event":"finished","run_id":"r3"}`), http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := post(engine, test.body, test.signature)
+			if recorder.Code != test.status {
+				t.Errorf("status = %d, want %d", recorder.Code, test.status)
+			}
+		})
+	}
+
+	if calls.Load() != 2 {
+		t.Errorf("handler called %d times, want 2", calls.Load())
+	}
+}
+
+func TestReceiverWithoutSecretRejectsEverything(t *testing.T) {
+	receiver := NewReceiver(Options{Name: "test", SignatureHeader: "X-Test-Signature", IDField: "run_id"})
+	err := receiver.Configure(Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := gin.New()
+	engine.POST("/callback", receiver.GinHandler())
+
+	empty := hex.EncodeToString(hmac.New(sha256.New, nil).Sum(nil))
+	if recorder := post(engine, "", empty); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", recorder.Code)
+	}
+}
+
+func TestConfigureSecretEnv(t *testing.T) {
+	receiver := NewReceiver(Options{Name: "test"})
+
+	err := receiver.Configure(Config{SecretEnv: "WEBHOOK_TEST_SECRET_UNSET"})
+	if err == nil {
+		t.Error("want an error for an unset secret_env")
+	}
+
+	t.Setenv("WEBHOOK_TEST_SECRET", testSecret)
+	err = receiver.Configure(Config{Secret: "ignored", SecretEnv: "WEBHOOK_TEST_SECRET"})
+	if err != nil || string(receiver.secret) != testSecret {
+		t.Errorf("secret = %q, %v, want the environment variable", receiver.secret, err)
+	}
+
+	err = receiver.Configure(Config{Dedupe: DedupeRedis})
+	if err == nil || !strings.Contains(err.Error(), "webhook/redisdedupe") {
+		t.Errorf("err = %v, want a hint to import redisdedupe", err)
+	}
+}
+
+func TestDispatchErrors(t *testing.T) {
+	receiver, engine := newTestReceiver(t)
+	receiver.On("finished", func(ctx context.Context, body []byte) error {
+		var data struct {
+			Count int `json:"count"`
+		}
+		return Decode(body, &data)
+	})
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"not json", `[1]`, http.StatusBadRequest},
+		{"missing id", `{"event":"finished"}`, http.StatusBadRequest},
+		{"unknown event", `{"event":"started","run_id":"r1"}`, http.StatusNotFound},
+		{"invalid data", `{"event":"finished","run_id":"r1","count":"many"}`, http.StatusBadRequest},
+		{"valid", `{"event":"finished","run_id":"r1","count":1}`, http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := post(engine, test.body, sign(test.body))
+			if recorder.Code != test.status {
+				t.Errorf("status = %d, want %d: %s", recorder.Code, test.status, recorder.Body)
+			}
+		})
+	}
+}
+
+func TestDuplicateDeliveries(t *testing.T) {
+	receiver, engine := newTestReceiver(t)
+
+	started := make(chan struct{})
+	finish := make(chan error)
+	var calls atomic.Int32
+	receiver.On("finished", func(ctx context.Context, body []byte) error {
+		if calls.Add(1) == 1 {
+			close(started)
+			return <-finish
+		}
+		return nil
+	})
+
+	body := `{"event":"finished","run_id":"r1"}`
+
+	var wg sync.WaitGroup
+	var first *httptest.ResponseRecorder
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		first = post(engine, body, sign(body))
+	}()
+	<-started
+
+	// a retry while the first delivery is in flight must not be acknowledged
+	if recorder := post(engine, body, sign(body)); recorder.Code != http.StatusConflict {
+		t.Errorf("retry during the first delivery: status = %d, want 409", recorder.Code)
+	}
+
+	// the first delivery fails, so its retry is handled
+	finish <- errors.New("downstream unavailable")
+	wg.Wait()
+	if first.Code != http.StatusInternalServerError {
+		t.Errorf("failed delivery: status = %d, want 500", first.Code)
+	}
+
+	if recorder := post(engine, body, sign(body)); recorder.Code != http.StatusOK {
+		t.Errorf("retry after the failure: status = %d, want 200", recorder.Code)
+	}
+	if recorder := post(engine, body, sign(body)); recorder.Code != http.StatusOK {
+		t.Errorf("duplicate after success: status = %d, want 200", recorder.Code)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("handler called %d times, want 2", calls.Load())
+	}
+}
+
+func TestPanicReleasesDelivery(t *testing.T) {
+	receiver, _ := newTestReceiver(t)
+	engine := gin.New()
+	engine.Use(gin.CustomRecovery(func(ctx *gin.Context, err any) {
+		ctx.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	engine.POST("/callback", receiver.GinHandler())
+
+	var calls atomic.Int32
+	receiver.On("finished", func(ctx context.Context, body []byte) error {
+		if calls.Add(1) == 1 {
+			panic("boom")
+		}
+		return nil
+	})
+
+	body := `{"event":"finished","run_id":"r1"}`
+	if recorder := post(engine, body, sign(body)); recorder.Code != http.StatusInternalServerError {
+		t.Errorf("panicking delivery: status = %d, want 500", recorder.Code)
+	}
+	if recorder := post(engine, body, sign(body)); recorder.Code != http.StatusOK {
+		t.Errorf("retry after the panic: status = %d, want 200", recorder.Code)
+	}
+}